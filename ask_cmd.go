@@ -9,38 +9,27 @@ import (
 	"os"
 	"os/signal"
 	"strings"
-	"time"
 
 	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/profile"
+	"github.com/yiblet/hlp/project"
+	"github.com/yiblet/hlp/prompt"
+	"github.com/yiblet/hlp/session"
 )
 
-const systemMessage = `
-For the user's following questions, let's think step by step in bash comments to output to make sure
-we output the correct bash command with comments. Make sure to ensure your output is always valid bash.
-
-use the following example to understand the desired response style:
-Question:
-How do I recursively alter all files to the standard chmod permissions in a directory
-
-Answer:
-# To recursively alter all files to the standard chmod permissions in a directory, you can use the following command with comments:
-# use the chmod command to change the file permissions recursively
-chmod -R 644 /path/to/directory/
-# -R option stands for recursive, which will apply the permissions to all files and subdirectories within the directory
-# 644 is the standard permission for files, which means the owner has read and write access, and others have only read access
-`
-
 type askCmd struct {
 	Question    []string `arg:"positional"`
 	MaxTokens   int      `arg:"--tokens,-t" default:"0" help:"the maximum amount of tokens allowed in the output"`
 	Temperature *float32 `arg:"--temp"`
-	Bash        bool     `arg:"--bash" help:"output only valid bash"`
+	Bash        bool     `arg:"--bash" help:"output only valid bash, same as --prompt bash"`
+	Prompt      string   `arg:"--prompt,-p" help:"use a named prompt from the library as the system prompt"`
 	Model       string   `arg:"--model,-m" help:"set openai model"`
 	Attach      []string `arg:"--attach,-a,separate" help:"attach additional files at the end of the message. pass '-' to pass in stdin"`
 	Once        bool     `arg:"--once,-o" help:"whether to just ask the model once"`
+	templateArgs
 }
 
-func (args *askCmd) buildContent(ctx context.Context) (string, error) {
+func (args *askCmd) buildContent(ctx context.Context, proj *project.Config) (string, error) {
 	var sb strings.Builder
 	for idx, q := range args.Question {
 		if idx != 0 {
@@ -54,23 +43,16 @@ func (args *askCmd) buildContent(ctx context.Context) (string, error) {
 		sb.WriteRune('\n')
 	}
 
-	for _, a := range args.Attach {
-		sb.WriteRune('\n')
-
-		var file *os.File
-		if a == "-" {
-			file = os.Stdin
-		} else {
-			var err error
-			file, err = os.Open(a)
-			if err != nil {
-				return "", err
-			}
-		}
-		defer file.Close()
+	// only the question is a template, attachments are passed verbatim
+	question, err := args.render(sb.String())
+	if err != nil {
+		return "", fmt.Errorf("cannot render question: %w", err)
+	}
+	sb.Reset()
+	sb.WriteString(question)
 
-		_, err := io.Copy(&sb, file)
-		if err != nil {
+	for _, a := range args.Attach {
+		if err := attach(&sb, a, proj); err != nil {
 			return "", err
 		}
 	}
@@ -78,18 +60,21 @@ func (args *askCmd) buildContent(ctx context.Context) (string, error) {
 	return sb.String(), nil
 }
 
-func (args *askCmd) messages(content string) []chat.Message {
-	if args.Bash {
-		return []chat.Message{
-			{Role: "system", Content: systemMessage},
-			{Role: "user", Content: content},
-		}
-	} else {
-		return []chat.Message{
-			{Role: "system", Content: content},
-		}
+func (args *askCmd) messages(library *prompt.Library, content string) ([]chat.Message, error) {
+	if args.Prompt == "" {
+		return session.Question("", content), nil
 	}
 
+	system, err := library.Get(args.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	system, err = args.render(system)
+	if err != nil {
+		return nil, fmt.Errorf("cannot render prompt %s: %w", args.Prompt, err)
+	}
+
+	return session.Question(system, content), nil
 }
 
 func (args *askCmd) poll(input *bufio.Reader) (string, bool, error) {
@@ -126,6 +111,9 @@ func (args *askCmd) poll(input *bufio.Reader) (string, bool, error) {
 }
 
 func (args *askCmd) init() {
+	if args.Bash && args.Prompt == "" {
+		args.Prompt = "bash"
+	}
 	for _, a := range args.Attach {
 		if a == "-" {
 			args.Once = true // if stdin is attached, we cant use it as a tty
@@ -134,50 +122,69 @@ func (args *askCmd) init() {
 	}
 }
 
-func (args *askCmd) Execute(ctx context.Context, config *config) error {
+func (args *askCmd) Execute(ctx context.Context, config *profile.Profile) error {
 	args.init()
 	model := args.Model
 	if model == "" {
 		model = strings.TrimSpace(config.Model())
 	}
-	client := config.Client()
+	client, err := config.Client()
+	if err != nil {
+		return err
+	}
+
+	if args.Prompt == "" {
+		args.Prompt = config.DefaultPrompt()
+	}
 
-	lastMessage := ""
-	content, err := args.buildContent(ctx)
+	content, err := args.buildContent(ctx, config.Project)
 	if err != nil {
 		return fmt.Errorf("cannot build message: %w", err)
 	}
 
 	input := bufio.NewReader(os.Stdin)
 
-	messages := args.messages(content)
+	messages, err := args.messages(config.Prompts(), content)
+	if err != nil {
+		return err
+	}
+	conversation := &session.Conversation{
+		Streamer:    client,
+		Model:       model,
+		MaxTokens:   args.MaxTokens,
+		Temperature: args.Temperature,
+		Messages:    messages,
+	}
 	for {
-		var response strings.Builder
-		out := io.MultiWriter(os.Stdout, &response)
-
-		ctx, cancel := context.WithTimeout(ctx, time.Minute*2)
-		defer cancel()
-		err = client.ChatStream(ctx, chat.Input{
-			Messages:    messages,
-			MaxTokens:   args.MaxTokens,
-			Temperature: args.Temperature,
-			Model:       model,
-		}, func(message string) error {
-			if message != "" {
-				lastMessage = message
-			}
-			_, err := fmt.Fprintf(out, "%s", message)
+		var response string
+		err = interrupts.run(ctx, func(ctx context.Context) error {
+			var err error
+			response, err = conversation.Generate(ctx, func(message string) error {
+				_, err := fmt.Fprintf(os.Stdout, "%s", message)
+				return err
+			})
 			return err
 		})
-		if err != nil {
+		interrupted := errors.Is(err, errInterrupted)
+		if err != nil && !interrupted {
 			return err
 		}
-		if len(lastMessage) == 0 || lastMessage[len(lastMessage)-1] != '\n' {
-			_, err := fmt.Fprintf(out, "\n")
+		if !strings.HasSuffix(response, "\n") {
+			_, err := fmt.Fprintf(os.Stdout, "\n")
 			if err != nil {
 				return err
 			}
+			response += "\n"
+		}
+		if interrupted {
+			// keep the partial reply in the conversation, marked as truncated
+			fmt.Printf("%s%s%s\n", colorYellow, strings.TrimSpace(truncationMarker), colorReset)
+			response += truncationMarker
+			if args.Once {
+				return errInterrupted
+			}
 		}
+		conversation.Add("assistant", response)
 
 		if args.Once {
 			break
@@ -200,11 +207,7 @@ func (args *askCmd) Execute(ctx context.Context, config *config) error {
 			return nil
 		}
 
-		messages = append(
-			messages,
-			chat.Message{Role: "assistant", Content: response.String()},
-			chat.Message{Role: "user", Content: line},
-		)
+		conversation.Add("user", line)
 	}
 	return nil
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/profile"
+	"github.com/yiblet/hlp/project"
+)
+
+func TestAskCmd(t *testing.T) {
+	t.Run("echo", func(t *testing.T) {
+		args := &askCmd{Question: []string{"what", "is", "up"}, Once: true}
+		var err error
+		output := captureStdout(t, func() {
+			err = args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{}))
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "what is up\n", output)
+	})
+
+	t.Run("template", func(t *testing.T) {
+		args := &askCmd{
+			Question:     []string{"review {{.file}}"},
+			Once:         true,
+			templateArgs: templateArgs{Vars: []string{"file=main.go"}},
+		}
+		var err error
+		output := captureStdout(t, func() {
+			err = args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{}))
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "review main.go\n", output)
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		args := &askCmd{Question: []string{"hello"}, Once: true}
+		var err error
+		captureStdout(t, func() {
+			err = args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{Failure: chat.FakeRateLimit}))
+		})
+		var statusErr *chat.FakeStatusError
+		assert.ErrorAs(t, err, &statusErr)
+	})
+
+	t.Run("project", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "go.lock"), []byte("locked\n"), 0644))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref\n"), 0644))
+
+		config := newFakeConfig(profile.FakeConfig{})
+		config.Project = &project.Config{
+			Prompt:  "terse",
+			Prompts: map[string]string{"terse": "be terse"},
+			Ignore:  []string{"*.lock"},
+		}
+
+		args := &askCmd{Question: []string{"review"}, Attach: []string{dir}, Once: true}
+		var err error
+		output := captureStdout(t, func() {
+			err = args.Execute(context.Background(), config)
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "terse", args.Prompt)
+		assert.Contains(t, output, "package main")
+		assert.NotContains(t, output, "locked")
+		assert.NotContains(t, output, "ref")
+	})
+}
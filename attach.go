@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yiblet/hlp/project"
+)
+
+// attach appends the file at path to sb. "-" attaches stdin. A directory is
+// attached file by file, each preceded by its path, skipping binary files
+// and the files the project ignores.
+func attach(sb *strings.Builder, path string, proj *project.Config) error {
+	if path == "-" {
+		sb.WriteRune('\n')
+		_, err := io.Copy(sb, os.Stdin)
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sb.WriteRune('\n')
+		sb.Write(buf)
+		return nil
+	}
+
+	return filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(path, file)
+		if err != nil || rel == "." {
+			return err
+		}
+		if proj.Ignored(filepath.ToSlash(rel), entry.IsDir()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if isBinary(buf) {
+			return nil
+		}
+		fmt.Fprintf(sb, "\n==> %s <==\n", file)
+		sb.Write(buf)
+		if len(buf) > 0 && buf[len(buf)-1] != '\n' {
+			sb.WriteRune('\n')
+		}
+		return nil
+	})
+}
+
+// isBinary reports whether buf looks like the content of a binary file.
+func isBinary(buf []byte) bool {
+	return bytes.IndexByte(buf[:min(len(buf), 8000)], 0) >= 0
+}
@@ -0,0 +1,228 @@
+// Package batch answers many independent requests read from JSON lines,
+// with bounded concurrency and rate limiting, and writes one JSON line per
+// result. A batch can be resumed by skipping the ids whose results were
+// already written.
+//
+// A request is either a conversation:
+//
+//	{"id": "1", "messages": [{"role": "user", "content": "hello"}]}
+//
+// or a named prompt and an input, both rendered as templates with vars:
+//
+//	{"id": "2", "prompt": "classify", "input": "{{.line}}", "vars": {"line": "disk full"}}
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/prompt"
+	"github.com/yiblet/hlp/session"
+)
+
+// ID identifies a request. It is a string or a number in the input and
+// always a string in the output.
+type ID string
+
+func (id *ID) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case string:
+		*id = ID(value)
+	case float64:
+		*id = ID(strconv.FormatFloat(value, 'f', -1, 64))
+	default:
+		return fmt.Errorf("id must be a string or a number")
+	}
+	return nil
+}
+
+// Request is a line of the input.
+type Request struct {
+	ID ID `json:"id"`
+	// Model overrides the model of the batch.
+	Model       string         `json:"model,omitempty"`
+	Messages    []chat.Message `json:"messages,omitempty"`
+	Prompt      string         `json:"prompt,omitempty"`
+	Input       string         `json:"input,omitempty"`
+	Vars        prompt.Vars    `json:"vars,omitempty"`
+	MaxTokens   int            `json:"max_tokens,omitempty"`
+	Temperature *float32       `json:"temperature,omitempty"`
+}
+
+// Defaults are the values of the requests that do not set their own.
+type Defaults struct {
+	Model  string
+	Prompt string
+	Input  string
+}
+
+// Read reads the requests in r, one JSON object per line. Blank lines are
+// skipped. A request without an id is identified by its line number.
+func Read(r io.Reader, defaults Defaults) ([]Request, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	var requests []Request
+	seen := map[ID]int{}
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var request Request
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil {
+			return nil, &InvalidRequestError{Line: line, Err: err}
+		}
+		if request.ID == "" {
+			request.ID = ID(strconv.Itoa(line))
+		}
+		if previous, ok := seen[request.ID]; ok {
+			return nil, &InvalidRequestError{Line: line, Err: fmt.Errorf("duplicate id %q, first used on line %d", request.ID, previous)}
+		}
+		seen[request.ID] = line
+
+		request.applyDefaults(defaults)
+		if err := request.validate(); err != nil {
+			return nil, &InvalidRequestError{Line: line, Err: err}
+		}
+		requests = append(requests, request)
+	}
+	return requests, scanner.Err()
+}
+
+func (r *Request) applyDefaults(defaults Defaults) {
+	if r.Model == "" {
+		r.Model = defaults.Model
+	}
+	if len(r.Messages) > 0 {
+		return
+	}
+	if r.Prompt == "" {
+		r.Prompt = defaults.Prompt
+	}
+	if r.Input == "" {
+		r.Input = defaults.Input
+	}
+}
+
+func (r *Request) validate() error {
+	switch {
+	case len(r.Messages) > 0 && (r.Prompt != "" || r.Input != ""):
+		return fmt.Errorf("messages cannot be combined with a prompt or input")
+	case len(r.Messages) == 0 && r.Input == "":
+		return fmt.Errorf("needs messages or an input")
+	}
+	for _, msg := range r.Messages {
+		if msg.Role != "system" && msg.Role != "user" && msg.Role != "assistant" {
+			return fmt.Errorf("invalid role %q", msg.Role)
+		}
+	}
+	return nil
+}
+
+// messages builds the conversation of the request.
+func (r *Request) messages(library *prompt.Library) ([]chat.Message, error) {
+	if len(r.Messages) > 0 {
+		return r.Messages, nil
+	}
+
+	content, err := r.render(r.Input)
+	if err != nil {
+		return nil, fmt.Errorf("cannot render input: %w", err)
+	}
+	var system string
+	if r.Prompt != "" {
+		if system, err = library.Get(r.Prompt); err != nil {
+			return nil, err
+		}
+		if system, err = r.render(system); err != nil {
+			return nil, fmt.Errorf("cannot render prompt %s: %w", r.Prompt, err)
+		}
+	}
+	return session.Question(system, content), nil
+}
+
+// render renders text as a template if the request has variables.
+func (r *Request) render(text string) (string, error) {
+	if len(r.Vars) == 0 {
+		return text, nil
+	}
+	return prompt.Render(text, r.Vars)
+}
+
+// Result is a line of the output.
+type Result struct {
+	ID     ID          `json:"id"`
+	Model  string      `json:"model"`
+	Output string      `json:"output"`
+	Usage  *chat.Usage `json:"usage,omitempty"`
+	Error  string      `json:"error,omitempty"`
+	// DurationMS is how long the request took in milliseconds.
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// Finished reads the results in r, as written by a previous run, and
+// returns the ones that succeeded. Results that failed and a last line cut
+// short by a crash are left out, so that those requests run again.
+func Finished(r io.Reader) ([]Result, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	var results []Result
+	for scanner.Scan() {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var result Result
+		if err := json.Unmarshal(data, &result); err != nil {
+			// the last line of a run that was killed
+			continue
+		}
+		if result.Error == "" && result.ID != "" {
+			results = append(results, result)
+		}
+	}
+	return results, scanner.Err()
+}
+
+// Skip returns the requests whose ids are not in finished.
+func Skip(requests []Request, finished []Result) []Request {
+	done := make(map[ID]bool, len(finished))
+	for _, result := range finished {
+		done[result.ID] = true
+	}
+	var remaining []Request
+	for _, request := range requests {
+		if !done[request.ID] {
+			remaining = append(remaining, request)
+		}
+	}
+	return remaining
+}
+
+// InvalidRequestError is returned for lines of the input that are not valid
+// requests.
+type InvalidRequestError struct {
+	Line int
+	Err  error
+}
+
+func (e *InvalidRequestError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, strings.TrimPrefix(e.Err.Error(), "json: "))
+}
+
+func (e *InvalidRequestError) Unwrap() error { return e.Err }
@@ -0,0 +1,189 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/prompt"
+)
+
+// echo replies with the system prompt and the last message. It fails
+// messages that contain "fail", and answers later messages sooner so that
+// they complete out of order.
+func echo(delay bool) chat.Streamer {
+	return chat.StreamerFunc(func(ctx context.Context, request chat.Input, onData func(string) error) error {
+		last := request.Messages[len(request.Messages)-1].Content
+		if strings.Contains(last, "fail") {
+			return errors.New("model refused")
+		}
+		if delay {
+			time.Sleep(time.Duration(10-len(last)) * 5 * time.Millisecond)
+		}
+		reply := last
+		if len(request.Messages) > 1 {
+			reply = request.Messages[0].Content + ": " + last
+		}
+		return onData(reply)
+	})
+}
+
+func readResults(t *testing.T, output string) []Result {
+	t.Helper()
+	var results []Result
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var result Result
+		require.NoError(t, json.Unmarshal([]byte(line), &result))
+		results = append(results, result)
+	}
+	return results
+}
+
+func TestRead(t *testing.T) {
+	input := `{"id": "a", "messages": [{"role": "user", "content": "hi"}]}
+
+{"id": 7, "vars": {"line": "disk full"}, "model": "gpt-4o"}
+{"input": "hello"}
+`
+	requests, err := Read(strings.NewReader(input), Defaults{Model: "gpt-4o-mini", Prompt: "classify", Input: "{{.line}}"})
+	require.NoError(t, err)
+	require.Len(t, requests, 3)
+
+	assert.Equal(t, ID("a"), requests[0].ID)
+	assert.Equal(t, "gpt-4o-mini", requests[0].Model)
+	assert.Empty(t, requests[0].Prompt, "defaults do not apply to conversations")
+
+	assert.Equal(t, ID("7"), requests[1].ID)
+	assert.Equal(t, "gpt-4o", requests[1].Model)
+	assert.Equal(t, "classify", requests[1].Prompt)
+	assert.Equal(t, "{{.line}}", requests[1].Input)
+
+	assert.Equal(t, ID("4"), requests[2].ID, "the line number is the default id")
+	assert.Equal(t, "hello", requests[2].Input)
+}
+
+func TestReadInvalid(t *testing.T) {
+	for name, test := range map[string]struct{ input, err string }{
+		"json":      {`{"id": 1`, "line 1: unexpected EOF"},
+		"field":     {`{"id": 1, "input": "a", "promt": "x"}`, `line 1: unknown field "promt"`},
+		"duplicate": {"{\"id\": 1, \"input\": \"a\"}\n{\"id\": \"1\", \"input\": \"b\"}", `line 2: duplicate id "1", first used on line 1`},
+		"empty":     {`{"id": 1}`, "line 1: needs messages or an input"},
+		"both":      {`{"messages": [{"role": "user", "content": "a"}], "input": "b"}`, "line 1: messages cannot be combined with a prompt or input"},
+		"role":      {`{"messages": [{"role": "bot", "content": "a"}]}`, `line 1: invalid role "bot"`},
+		"id":        {`{"id": [1], "input": "a"}`, "line 1: id must be a string or a number"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(test.input), Defaults{})
+			var invalid *InvalidRequestError
+			require.ErrorAs(t, err, &invalid)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	library := prompt.NewLibrary(t.TempDir())
+	require.NoError(t, library.Add("classify", "classify as {{.level}}", false))
+
+	requests, err := Read(strings.NewReader(`{"id": "1", "input": "a"}
+{"id": "2", "input": "fail"}
+{"id": "3", "prompt": "classify", "input": "{{.line}}", "vars": {"line": "oom", "level": "error"}}
+{"id": "4", "prompt": "missing", "input": "b"}
+{"id": "5", "messages": [{"role": "system", "content": "sys"}, {"role": "user", "content": "c"}]}
+`), Defaults{Model: "m"})
+	require.NoError(t, err)
+
+	for _, ordered := range []bool{true, false} {
+		var out bytes.Buffer
+		runner := &Runner{Streamer: echo(true), Prompts: library, Concurrency: 5, Ordered: ordered}
+		stats, err := runner.Run(context.Background(), requests, &out)
+		require.NoError(t, err)
+		assert.Equal(t, Stats{Succeeded: 3, Failed: 2}, stats)
+
+		results := map[ID]Result{}
+		var ids []ID
+		for _, result := range readResults(t, out.String()) {
+			results[result.ID] = result
+			ids = append(ids, result.ID)
+		}
+		if ordered {
+			assert.Equal(t, []ID{"1", "2", "3", "4", "5"}, ids)
+		} else {
+			assert.NotEqual(t, []ID{"1", "2", "3", "4", "5"}, ids, "the slower requests are written last")
+		}
+
+		assert.Equal(t, "a", results["1"].Output)
+		assert.Equal(t, "m", results["1"].Model)
+		require.NotNil(t, results["1"].Usage)
+		assert.True(t, results["1"].Usage.Estimated, "the usage is estimated when the streamer reports none")
+		assert.Equal(t, "model refused", results["2"].Error)
+		assert.Nil(t, results["2"].Usage)
+		assert.Equal(t, "classify as error: oom", results["3"].Output)
+		assert.Contains(t, results["4"].Error, "missing")
+		assert.Equal(t, "sys: c", results["5"].Output)
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	streamer := chat.StreamerFunc(func(ctx context.Context, request chat.Input, onData func(string) error) error {
+		chat.ReportUsage(ctx, chat.Usage{InputTokens: 12, OutputTokens: 3})
+		return onData("ok")
+	})
+	var out bytes.Buffer
+	_, err := (&Runner{Streamer: streamer}).Run(context.Background(), []Request{{ID: "1", Input: "a"}}, &out)
+	require.NoError(t, err)
+	assert.Equal(t, &chat.Usage{InputTokens: 12, OutputTokens: 3}, readResults(t, out.String())[0].Usage)
+}
+
+func TestRunRate(t *testing.T) {
+	requests := []Request{{ID: "1", Input: "a"}, {ID: "2", Input: "b"}, {ID: "3", Input: "c"}}
+	start := time.Now()
+	runner := &Runner{Streamer: echo(false), RequestsPerMinute: 60 * 20}
+	_, err := runner.Run(context.Background(), requests, &bytes.Buffer{})
+	require.NoError(t, err)
+	// the first request starts at once and the others 50ms apart
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestRunCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	streamer := chat.StreamerFunc(func(ctx context.Context, request chat.Input, onData func(string) error) error {
+		if request.Messages[0].Content == "slow" {
+			cancel()
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return onData("ok")
+	})
+	requests := []Request{{ID: "1", Input: "fast"}, {ID: "2", Input: "slow"}, {ID: "3", Input: "fast"}}
+
+	var out bytes.Buffer
+	runner := &Runner{Streamer: streamer, Concurrency: 1, Ordered: true}
+	stats, err := runner.Run(ctx, requests, &out)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, Stats{Succeeded: 1}, stats, "requests cut short are not written")
+	assert.Len(t, readResults(t, out.String()), 1)
+}
+
+func TestResume(t *testing.T) {
+	previous := `{"id":"1","model":"m","output":"a","duration_ms":1}
+{"id":"2","model":"m","output":"","error":"model refused","duration_ms":1}
+{"id":"3","model":"m","output":"c","duration_ms":1}
+{"id":"4","model":"m","out`
+	finished, err := Finished(strings.NewReader(previous))
+	require.NoError(t, err)
+	require.Len(t, finished, 2)
+	assert.Equal(t, ID("1"), finished[0].ID)
+	assert.Equal(t, ID("3"), finished[1].ID)
+
+	requests := []Request{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}}
+	remaining := Skip(requests, finished)
+	assert.Equal(t, []Request{{ID: "2"}, {ID: "4"}}, remaining)
+}
@@ -0,0 +1,249 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/prompt"
+	"github.com/yiblet/hlp/session"
+)
+
+// DefaultConcurrency is the number of requests a Runner runs at once if none
+// is set.
+const DefaultConcurrency = 4
+
+// Runner runs batches.
+type Runner struct {
+	// Streamer answers the requests.
+	Streamer chat.Streamer
+	// Prompts is the library the prompts of requests are read from.
+	Prompts *prompt.Library
+	// Concurrency is the number of requests run at once,
+	// DefaultConcurrency if zero.
+	Concurrency int
+	// RequestsPerMinute limits how often requests are started. Zero means
+	// no limit.
+	RequestsPerMinute float64
+	// Ordered writes the results in the order of the requests instead of as
+	// each completes.
+	Ordered bool
+	// OnResult, if set, is called with every result once it is written,
+	// from a single goroutine at a time.
+	OnResult func(Result)
+}
+
+// Stats counts the results of a run.
+type Stats struct {
+	Succeeded int
+	Failed    int
+}
+
+// Run answers requests and writes every result to w as a line of JSON.
+// Requests that fail are written with an error and do not stop the run.
+// When ctx is cancelled no more requests are started, the requests that
+// were cut short are not written, and ctx.Err() is returned.
+func (r *Runner) Run(ctx context.Context, requests []Request, w io.Writer) (Stats, error) {
+	concurrency := r.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	limit := newLimiter(r.RequestsPerMinute)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	out := &writer{w: w, ordered: r.Ordered, pending: map[int]Result{}, onResult: r.OnResult, cancel: cancel}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < concurrency; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result := r.run(ctx, &requests[i])
+				if result.Error != "" && ctx.Err() != nil {
+					out.skip(i)
+					continue
+				}
+				out.write(i, result)
+			}
+		}()
+	}
+
+dispatch:
+	for i := range requests {
+		if err := limit.wait(ctx); err != nil {
+			break
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if out.err != nil {
+		return out.stats, out.err
+	}
+	return out.stats, ctx.Err()
+}
+
+func (r *Runner) run(ctx context.Context, request *Request) Result {
+	result := Result{ID: request.ID, Model: request.Model}
+	start := time.Now()
+	defer func() { result.DurationMS = time.Since(start).Milliseconds() }()
+
+	messages, err := request.messages(r.Prompts)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var usage *chat.Usage
+	ctx = chat.WithUsage(ctx, func(u chat.Usage) { usage = &u })
+	conversation := &session.Conversation{
+		Streamer:    r.Streamer,
+		Model:       request.Model,
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+		Messages:    messages,
+	}
+	result.Output, err = conversation.Generate(ctx, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if usage == nil {
+		estimate := chat.EstimateUsage(messages, result.Output)
+		usage = &estimate
+	}
+	result.Usage = usage
+	return result
+}
+
+// writer writes results as lines of JSON, holding back results that
+// complete early when the output is ordered.
+type writer struct {
+	mu       sync.Mutex
+	w        io.Writer
+	ordered  bool
+	next     int
+	pending  map[int]Result
+	skipped  map[int]bool
+	onResult func(Result)
+	cancel   func()
+	stats    Stats
+	err      error
+}
+
+func (w *writer) write(i int, result Result) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.ordered {
+		w.emit(result)
+		return
+	}
+	w.pending[i] = result
+	w.flush()
+}
+
+// skip marks a request that will not be written, so that the ordered
+// results after it are not held back.
+func (w *writer) skip(i int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.ordered {
+		return
+	}
+	if w.skipped == nil {
+		w.skipped = map[int]bool{}
+	}
+	w.skipped[i] = true
+	w.flush()
+}
+
+func (w *writer) flush() {
+	for {
+		if result, ok := w.pending[w.next]; ok {
+			delete(w.pending, w.next)
+			w.emit(result)
+		} else if w.skipped[w.next] {
+			delete(w.skipped, w.next)
+		} else {
+			return
+		}
+		w.next++
+	}
+}
+
+func (w *writer) emit(result Result) {
+	if w.err != nil {
+		return
+	}
+	line, err := json.Marshal(result)
+	if err == nil {
+		// a single write per line, so that a crash leaves at most one
+		// partial line behind
+		_, err = w.w.Write(append(line, '\n'))
+	}
+	if err != nil {
+		w.err = err
+		w.cancel()
+		return
+	}
+	if result.Error == "" {
+		w.stats.Succeeded++
+	} else {
+		w.stats.Failed++
+	}
+	if w.onResult != nil {
+		w.onResult(result)
+	}
+}
+
+// limiter spaces out the start of requests.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newLimiter(perMinute float64) *limiter {
+	if perMinute <= 0 {
+		return &limiter{}
+	}
+	return &limiter{interval: time.Duration(float64(time.Minute) / perMinute)}
+}
+
+// wait blocks until the next request may start.
+func (l *limiter) wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
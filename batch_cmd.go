@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/yiblet/hlp/batch"
+	"github.com/yiblet/hlp/profile"
+)
+
+type batchCmd struct {
+	Input       string  `arg:"positional,required" help:"the requests, one JSON object per line"`
+	Output      string  `arg:"positional,required" help:"the file the results are appended to, one JSON object per line"`
+	Model       string  `arg:"--model,-m" help:"the model of the requests that do not name one"`
+	Prompt      string  `arg:"--prompt,-p" help:"a named prompt from the library for the requests that do not name one"`
+	Template    string  `arg:"--template" help:"the input of the requests that have vars but no input, e.g. '{{.line}}'"`
+	Concurrency int     `arg:"--concurrency,-j" default:"4" help:"the number of requests run at once"`
+	Rate        float64 `arg:"--rate" help:"the most requests started per minute, unlimited if zero"`
+	Ordered     bool    `arg:"--ordered" help:"write the results in the order of the requests instead of as each completes"`
+	Restart     bool    `arg:"--restart" help:"discard the results of a previous run instead of resuming it"`
+}
+
+func (args *batchCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	in, err := os.Open(args.Input)
+	if err != nil {
+		return err
+	}
+	model := args.Model
+	if model == "" {
+		model = strings.TrimSpace(config.Model())
+	}
+	if args.Prompt == "" {
+		args.Prompt = config.DefaultPrompt()
+	}
+	requests, err := batch.Read(in, batch.Defaults{Model: model, Prompt: args.Prompt, Input: args.Template})
+	in.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", args.Input, err)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	remaining := requests
+	if args.Restart {
+		flags |= os.O_TRUNC
+	} else if remaining, err = resumeBatch(args.Output, requests); err != nil {
+		return err
+	}
+	if skipped := len(requests) - len(remaining); skipped > 0 {
+		fmt.Fprintf(os.Stderr, "skipping %d of %d requests finished by a previous run\n", skipped, len(requests))
+	}
+	if len(remaining) == 0 {
+		return nil
+	}
+
+	client, err := config.Client()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(args.Output, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	runner := &batch.Runner{
+		Streamer:          client,
+		Prompts:           config.Prompts(),
+		Concurrency:       args.Concurrency,
+		RequestsPerMinute: args.Rate,
+		Ordered:           args.Ordered,
+		OnResult: func(result batch.Result) {
+			if result.Error != "" {
+				fmt.Fprintf(os.Stderr, "%sERROR%s  %s: %s\n", colorRed, colorReset, result.ID, result.Error)
+			}
+		},
+	}
+
+	var stats batch.Stats
+	err = interrupts.run(ctx, func(ctx context.Context) error {
+		var err error
+		stats, err = runner.Run(ctx, remaining, out)
+		return err
+	})
+	fmt.Fprintf(os.Stderr, "%d succeeded, %d failed, %d not run\n",
+		stats.Succeeded, stats.Failed, len(remaining)-stats.Succeeded-stats.Failed)
+	if err != nil {
+		if errors.Is(err, errInterrupted) {
+			return fmt.Errorf("%w: run the same command again to resume", err)
+		}
+		return err
+	}
+	if stats.Failed > 0 {
+		return fmt.Errorf("%d of %d requests failed", stats.Failed, len(remaining))
+	}
+	return nil
+}
+
+// resumeBatch returns the requests that have no successful result in the
+// output of a previous run. The output is rewritten without its failed
+// results and any line cut short by a crash, so that the requests that run
+// again are not listed twice.
+func resumeBatch(path string, requests []batch.Request) ([]batch.Request, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return requests, nil
+	} else if err != nil {
+		return nil, err
+	}
+	finished, err := batch.Finished(f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := writeFileAtomic(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		for _, result := range finished {
+			if err := enc.Encode(result); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return batch.Skip(requests, finished), nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/profile"
+)
+
+func TestBatchCmd(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.jsonl")
+	out := filepath.Join(dir, "out.jsonl")
+	require.NoError(t, os.WriteFile(in, []byte(`{"id": "a", "vars": {"line": "disk full"}}
+{"id": "b", "vars": {"line": "user login"}}
+{"id": "c", "messages": [{"role": "user", "content": "hello"}]}
+`), 0644))
+
+	t.Run("failures", func(t *testing.T) {
+		args := &batchCmd{Input: in, Output: out, Template: "classify: {{.line}}", Concurrency: 2}
+		err := args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{Failure: chat.FakeRateLimit}))
+		assert.EqualError(t, err, "3 of 3 requests failed")
+
+		output, err := os.ReadFile(out)
+		require.NoError(t, err)
+		assert.Equal(t, 3, strings.Count(string(output), `"error":"fake provider: 429 rate limit exceeded"`))
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		// the failed requests run again, and the last line was cut short
+		f, err := os.OpenFile(out, os.O_APPEND|os.O_WRONLY, 0)
+		require.NoError(t, err)
+		_, err = f.WriteString(`{"id":"a","model":"gpt-4o-mini","output":"done","duration_ms":1}` + "\n" + `{"id":"b","mod`)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		args := &batchCmd{Input: in, Output: out, Template: "classify: {{.line}}", Ordered: true}
+		require.NoError(t, args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{})))
+
+		output, err := os.ReadFile(out)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		require.Len(t, lines, 3)
+		assert.Contains(t, lines[0], `"id":"a","model":"gpt-4o-mini","output":"done"`)
+		assert.Contains(t, lines[1], `"id":"b","model":"gpt-4o-mini","output":"classify: user login"`)
+		assert.Contains(t, lines[2], `"id":"c","model":"gpt-4o-mini","output":"hello"`)
+		assert.Contains(t, lines[2], `"usage":{"input_tokens":`)
+	})
+
+	t.Run("finished", func(t *testing.T) {
+		before, err := os.ReadFile(out)
+		require.NoError(t, err)
+		args := &batchCmd{Input: in, Output: out, Template: "{{.line}}"}
+		require.NoError(t, args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{Failure: chat.FakeRateLimit})))
+		after, err := os.ReadFile(out)
+		require.NoError(t, err)
+		assert.Equal(t, string(before), string(after))
+	})
+
+	t.Run("model flag", func(t *testing.T) {
+		out := filepath.Join(dir, "model.jsonl")
+		args := &batchCmd{Input: in, Output: out, Model: "gpt-4o", Template: "{{.line}}"}
+		require.NoError(t, args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{})))
+		output, err := os.ReadFile(out)
+		require.NoError(t, err)
+		assert.Equal(t, 3, strings.Count(string(output), `"model":"gpt-4o"`))
+	})
+
+	t.Run("restart", func(t *testing.T) {
+		args := &batchCmd{Input: in, Output: out, Template: "{{.line}}", Restart: true, Ordered: true}
+		require.NoError(t, args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{})))
+		output, err := os.ReadFile(out)
+		require.NoError(t, err)
+		assert.Equal(t, 3, strings.Count(string(output), "\n"))
+		assert.Contains(t, string(output), `"output":"disk full"`)
+	})
+}
@@ -0,0 +1,257 @@
+// Package cache stores chat replies on disk keyed on the content of their
+// request, so identical requests can be answered without calling the model
+// again.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yiblet/hlp/chat"
+)
+
+const (
+	// DefaultTTL is how long entries are kept if Store.TTL is zero.
+	DefaultTTL = time.Hour * 24 * 7
+	// DefaultMaxSize is the size cap in bytes if Store.MaxSize is zero.
+	DefaultMaxSize = 64 << 20
+)
+
+const extension = ".json"
+
+// entry is the content of a cache file.
+type entry struct {
+	Created time.Time `json:"created"`
+	Model   string    `json:"model"`
+	Content string    `json:"content"`
+}
+
+// Stats describes the content of a Store.
+type Stats struct {
+	Entries int
+	Size    int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// Store is a content addressed cache of replies stored in Dir. Entries
+// expire after TTL and the least recently used entries are evicted once the
+// cache grows beyond MaxSize bytes.
+type Store struct {
+	Dir     string
+	TTL     time.Duration
+	MaxSize int64
+
+	now func() time.Time
+}
+
+// NewStore creates a Store in dir. A zero ttl or maxSize selects the
+// default.
+func NewStore(dir string, ttl time.Duration, maxSize int64) *Store {
+	return &Store{Dir: dir, TTL: ttl, MaxSize: maxSize}
+}
+
+func (s *Store) time() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+func (s *Store) ttl() time.Duration {
+	if s.TTL <= 0 {
+		return DefaultTTL
+	}
+	return s.TTL
+}
+
+func (s *Store) maxSize() int64 {
+	if s.MaxSize <= 0 {
+		return DefaultMaxSize
+	}
+	return s.MaxSize
+}
+
+// Key returns the cache key of request. namespace separates requests that
+// are answered by different providers.
+func Key(namespace string, request chat.Input) string {
+	buf, _ := json.Marshal(struct {
+		Namespace   string         `json:"namespace"`
+		Model       string         `json:"model"`
+		Messages    []chat.Message `json:"messages"`
+		Temperature *float32       `json:"temperature"`
+		MaxTokens   int            `json:"max_tokens"`
+	}{namespace, request.Model, request.Messages, request.Temperature, request.MaxTokens})
+	hash := sha256.Sum256(buf)
+	return hex.EncodeToString(hash[:])
+}
+
+func (s *Store) path(key string) string {
+	return filepath.Join(s.Dir, key+extension)
+}
+
+// Get returns the reply cached under key. Expired entries are removed and
+// reported as missing.
+func (s *Store) Get(key string) (string, bool, error) {
+	path := s.path(key)
+	buf, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	var e entry
+	if err := json.Unmarshal(buf, &e); err != nil || s.time().Sub(e.Created) > s.ttl() {
+		// invalid and expired entries are dropped
+		os.Remove(path)
+		return "", false, nil
+	}
+
+	// the modification time tracks the last use for evictions
+	now := s.time()
+	if err := os.Chtimes(path, now, now); err != nil {
+		return "", false, err
+	}
+	return e.Content, true, nil
+}
+
+// Put stores content under key and evicts entries until the cache fits its
+// size cap.
+func (s *Store) Put(key, model, content string) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return fmt.Errorf("cannot create cache directory: %w", err)
+	}
+
+	buf, err := json.Marshal(entry{Created: s.time(), Model: model, Content: content})
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(s.Dir, "."+key+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(buf); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	path := s.path(key)
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return err
+	}
+	now := s.time()
+	if err := os.Chtimes(path, now, now); err != nil {
+		return err
+	}
+
+	return s.evict()
+}
+
+type file struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (s *Store) files() ([]file, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]file, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || !strings.HasSuffix(e.Name(), extension) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{
+			path:    filepath.Join(s.Dir, e.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	return files, nil
+}
+
+// evict removes the least recently used entries until the cache fits its
+// size cap.
+func (s *Store) evict() error {
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	for _, f := range files {
+		size += f.size
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files {
+		if size <= s.maxSize() {
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		size -= f.size
+	}
+	return nil
+}
+
+// Clear removes every entry of the cache.
+func (s *Store) Clear() error {
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stats summarizes the entries of the cache. Oldest and Newest refer to the
+// last use of an entry.
+func (s *Store) Stats() (Stats, error) {
+	files, err := s.files()
+	if err != nil {
+		return Stats{}, err
+	}
+
+	var stats Stats
+	for _, f := range files {
+		stats.Entries++
+		stats.Size += f.size
+		if stats.Oldest.IsZero() || f.modTime.Before(stats.Oldest) {
+			stats.Oldest = f.modTime
+		}
+		if f.modTime.After(stats.Newest) {
+			stats.Newest = f.modTime
+		}
+	}
+	return stats, nil
+}
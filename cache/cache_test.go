@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/chat"
+)
+
+func newTestStore(t *testing.T, now *time.Time) *Store {
+	t.Helper()
+	store := NewStore(t.TempDir(), time.Hour, 0)
+	store.now = func() time.Time { return *now }
+	return store
+}
+
+func TestStore(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	store := newTestStore(t, &now)
+
+	_, ok, err := store.Get("missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, store.Put("key", "model", "content"))
+	content, ok, err := store.Get("key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "content", content)
+
+	stats, err := store.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Entries)
+
+	now = now.Add(2 * time.Hour)
+	_, ok, err = store.Get("key")
+	require.NoError(t, err)
+	assert.False(t, ok, "expired entries are dropped")
+
+	stats, err = store.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Entries)
+}
+
+func TestStoreEviction(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	store := newTestStore(t, &now)
+
+	content := strings.Repeat("x", 100)
+	require.NoError(t, store.Put("a", "model", content))
+	stats, err := store.Stats()
+	require.NoError(t, err)
+	store.MaxSize = stats.Size * 2
+
+	now = now.Add(time.Second)
+	require.NoError(t, store.Put("b", "model", content))
+
+	// using a refreshes it so that b is the least recently used entry
+	now = now.Add(time.Second)
+	_, ok, err := store.Get("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	now = now.Add(time.Second)
+	require.NoError(t, store.Put("c", "model", content))
+
+	for key, expected := range map[string]bool{"a": true, "b": false, "c": true} {
+		_, ok, err := store.Get(key)
+		require.NoError(t, err)
+		assert.Equal(t, expected, ok, "entry %s", key)
+	}
+
+	require.NoError(t, store.Clear())
+	stats, err = store.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Entries)
+}
+
+// countingStreamer counts its calls and replies with a fixed response.
+type countingStreamer struct {
+	calls    int
+	response string
+	err      error
+}
+
+func (c *countingStreamer) ChatStream(ctx context.Context, request chat.Input, onData func(message string) error) error {
+	c.calls++
+	if c.err != nil {
+		return c.err
+	}
+	return onData(c.response)
+}
+
+func TestStreamer(t *testing.T) {
+	now := time.Now()
+	inner := &countingStreamer{response: "cached reply\nsecond line"}
+	streamer := &Streamer{Streamer: inner, Store: newTestStore(t, &now), Namespace: "test"}
+	input := chat.Input{Model: "model", Messages: []chat.Message{{Role: "user", Content: "hello"}}}
+
+	chatStream := func(ctx context.Context, input chat.Input) ([]string, error) {
+		var chunks []string
+		err := streamer.ChatStream(ctx, input, func(message string) error {
+			chunks = append(chunks, message)
+			return nil
+		})
+		return chunks, err
+	}
+
+	chunks, err := chatStream(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cached reply\nsecond line"}, chunks)
+
+	chunks, err = chatStream(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cached", " reply", "\nsecond", " line"}, chunks, "cached replies are streamed")
+	assert.Equal(t, 1, inner.calls)
+
+	_, err = chatStream(SkipLookup(context.Background()), input)
+	require.NoError(t, err)
+	assert.Equal(t, 2, inner.calls)
+
+	temperature := float32(0.5)
+	input.Temperature = &temperature
+	_, err = chatStream(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, 3, inner.calls, "a different temperature is a different request")
+
+	inner.err = errors.New("failed")
+	input.Model = "other-model"
+	_, err = chatStream(context.Background(), input)
+	assert.Error(t, err)
+	inner.err = nil
+	_, err = chatStream(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, 5, inner.calls, "failed replies are not cached")
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/yiblet/hlp/chat"
+)
+
+type skipLookupKey struct{}
+
+// SkipLookup returns a context whose requests are always sent to the model.
+// Their replies are still stored in the cache. This is used when a new reply
+// is wanted for a request that was answered before.
+func SkipLookup(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipLookupKey{}, true)
+}
+
+func skipsLookup(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipLookupKey{}).(bool)
+	return skip
+}
+
+// Streamer answers requests from a Store and sends the others to the wrapped
+// Streamer, storing their complete replies. Cached replies are streamed word
+// by word like a regular reply.
+type Streamer struct {
+	Streamer chat.Streamer
+	Store    *Store
+	// Namespace separates the cache entries of different providers.
+	Namespace string
+}
+
+func (s *Streamer) ChatStream(ctx context.Context, request chat.Input, onData func(message string) error) error {
+	key := Key(s.Namespace, request)
+
+	if !skipsLookup(ctx) {
+		content, ok, err := s.Store.Get(key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return replay(content, onData)
+		}
+	}
+
+	var reply strings.Builder
+	err := s.Streamer.ChatStream(ctx, request, func(message string) error {
+		reply.WriteString(message)
+		return onData(message)
+	})
+	if err != nil {
+		return err
+	}
+	return s.Store.Put(key, request.Model, reply.String())
+}
+
+// replay sends content to onData in chunks that each start with the
+// whitespace in front of a word.
+func replay(content string, onData func(message string) error) error {
+	start := 0
+	for i, r := range content {
+		if i > start && unicode.IsSpace(r) && !unicode.IsSpace(rune(content[i-1])) {
+			if err := onData(content[start:i]); err != nil {
+				return err
+			}
+			start = i
+		}
+	}
+	if start < len(content) {
+		return onData(content[start:])
+	}
+	return nil
+}
+
+// ensure that Streamer implements the chat.Streamer interface
+var _ chat.Streamer = (*Streamer)(nil)
+
+// Middleware returns a chat.Middleware that wraps a Streamer in a cache
+// Streamer.
+func Middleware(store *Store, namespace string) chat.Middleware {
+	return func(next chat.Streamer) chat.Streamer {
+		return &Streamer{Streamer: next, Store: store, Namespace: namespace}
+	}
+}
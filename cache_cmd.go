@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yiblet/hlp/profile"
+)
+
+type cacheCmd struct {
+	Clear *cacheClearCmd `arg:"subcommand:clear" help:"remove every cached response"`
+	Stats *cacheStatsCmd `arg:"subcommand:stats" help:"show the size of the response cache"`
+}
+
+func (c *cacheCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	switch {
+	case c.Clear != nil:
+		return c.Clear.Execute(ctx, config)
+	case c.Stats != nil:
+		return c.Stats.Execute(ctx, config)
+	default:
+		return writeHelp(c, os.Stderr)
+	}
+}
+
+type cacheClearCmd struct{}
+
+func (c *cacheClearCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	if err := config.CacheStore().Clear(); err != nil {
+		return err
+	}
+	fmt.Printf("cache cleared\n")
+	return nil
+}
+
+type cacheStatsCmd struct{}
+
+func (c *cacheStatsCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	store := config.CacheStore()
+	stats, err := store.Stats()
+	if err != nil {
+		return err
+	}
+
+	enabled := config.Cache != nil && config.Cache.Enabled
+	fmt.Printf("enabled:   %t\n", enabled)
+	fmt.Printf("directory: %s\n", store.Dir)
+	fmt.Printf("entries:   %d\n", stats.Entries)
+	fmt.Printf("size:      %.1f KiB\n", float64(stats.Size)/1024)
+	if stats.Entries > 0 {
+		fmt.Printf("oldest:    %s\n", stats.Oldest.Format(time.RFC3339))
+		fmt.Printf("newest:    %s\n", stats.Newest.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Package cassette records HTTP exchanges to a directory and replays them
+// later without touching the network. Streamed responses are recorded chunk
+// by chunk together with their timing so that replays look like the original
+// stream.
+package cassette
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Interaction is a single recorded HTTP exchange.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the recorded part of a request. Request headers are not
+// recorded since they usually carry credentials.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	Status     string      `json:"status"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Chunks     []Chunk     `json:"chunks"`
+}
+
+// Chunk is a piece of a response body.
+type Chunk struct {
+	// Delay is the time between the previous chunk, or the response
+	// headers, and this chunk.
+	Delay time.Duration `json:"delay"`
+	Data  string        `json:"data"`
+}
+
+// skippedHeaders are the response headers that are not recorded.
+var skippedHeaders = map[string]bool{
+	"Set-Cookie": true,
+	"Date":       true,
+}
+
+// Key identifies the requests that match each other. Only the method, the
+// path and query of the url and the body are taken into account so that a
+// cassette can be replayed against any host.
+func Key(method, url, body string) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n%s", method, url, body)
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// requestURL returns the part of the url of req that is recorded.
+func requestURL(req *http.Request) string {
+	return req.URL.RequestURI()
+}
+
+// path returns the file of the n-th (0-based) interaction with key in dir.
+func path(dir, key string, n int) string {
+	return filepath.Join(dir, fmt.Sprintf("%s-%d.json", key, n))
+}
+
+func readInteraction(path string) (*Interaction, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var interaction Interaction
+	if err := json.Unmarshal(buf, &interaction); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return &interaction, nil
+}
+
+func writeInteraction(path string, interaction *Interaction) error {
+	buf, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(buf, '\n'), 0644)
+}
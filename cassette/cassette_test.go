@@ -0,0 +1,81 @@
+package cassette_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/cassette"
+)
+
+func post(t *testing.T, client *http.Client, url, body string) (*http.Response, string, error) {
+	t.Helper()
+	resp, err := client.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(buf), nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for _, word := range []string{"first", "second"} {
+			io.WriteString(w, "data: "+word+" "+string(body)+"\n\n")
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	recorder, err := cassette.NewRecorder(dir, http.DefaultTransport)
+	require.NoError(t, err)
+	recordClient := &http.Client{Transport: recorder}
+
+	_, recorded1, err := post(t, recordClient, server.URL+"/v1/chat", "one")
+	require.NoError(t, err)
+	_, recorded2, err := post(t, recordClient, server.URL+"/v1/chat", "one")
+	require.NoError(t, err)
+	assert.Equal(t, "data: first one\n\ndata: second one\n\n", recorded1)
+	assert.Equal(t, 2, requests)
+
+	replayer, err := cassette.NewReplayer(dir, true)
+	require.NoError(t, err)
+	replayClient := &http.Client{Transport: replayer}
+
+	// the cassette is replayed against any host
+	start := time.Now()
+	resp, replayed1, err := post(t, replayClient, "http://example.invalid/v1/chat", "one")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond, "chunk timing is replayed")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, recorded1, replayed1)
+
+	_, replayed2, err := post(t, replayClient, "http://example.invalid/v1/chat", "one")
+	require.NoError(t, err)
+	assert.Equal(t, recorded2, replayed2)
+	assert.Equal(t, 2, requests, "replays do not reach the server")
+
+	var noMatch *cassette.NoMatchError
+
+	// every recorded response is only replayed once
+	_, _, err = post(t, replayClient, "http://example.invalid/v1/chat", "one")
+	assert.ErrorAs(t, err, &noMatch)
+
+	_, _, err = post(t, replayClient, "http://example.invalid/v1/chat", "two")
+	assert.ErrorAs(t, err, &noMatch)
+}
@@ -0,0 +1,142 @@
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Recorder is an http.RoundTripper that records every exchange made through
+// it into Dir. A response is written once its body has been read completely
+// or closed.
+type Recorder struct {
+	Dir   string
+	Inner http.RoundTripper
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewRecorder creates a Recorder that records the exchanges of inner into
+// dir.
+func NewRecorder(dir string, inner http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create cassette directory: %w", err)
+	}
+	return &Recorder{Dir: dir, Inner: inner}, nil
+}
+
+// next returns the file the next interaction with key is recorded to.
+func (r *Recorder) next(key string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.counts == nil {
+		r.counts = map[string]int{}
+	}
+	n := r.counts[key]
+	r.counts[key]++
+	return path(r.Dir, key, n)
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.Inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    requestURL(req),
+			Body:   body,
+		},
+		Response: Response{
+			Status:     resp.Status,
+			StatusCode: resp.StatusCode,
+			Header:     http.Header{},
+		},
+	}
+	for name, values := range resp.Header {
+		if !skippedHeaders[http.CanonicalHeaderKey(name)] {
+			interaction.Response.Header[name] = values
+		}
+	}
+
+	resp.Body = &recordingBody{
+		inner:       resp.Body,
+		interaction: interaction,
+		path:        r.next(Key(req.Method, interaction.Request.URL, body)),
+		last:        time.Now(),
+	}
+	return resp, nil
+}
+
+// readBody reads the body of req and replaces it so it can be sent.
+func readBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	buf, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(buf))
+	return string(buf), nil
+}
+
+// recordingBody records a response body chunk by chunk as it is read.
+type recordingBody struct {
+	inner       io.ReadCloser
+	interaction *Interaction
+	path        string
+	last        time.Time
+
+	once sync.Once
+	err  error
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.inner.Read(p)
+	if n > 0 {
+		now := time.Now()
+		b.interaction.Response.Chunks = append(b.interaction.Response.Chunks, Chunk{
+			Delay: now.Sub(b.last),
+			Data:  string(p[:n]),
+		})
+		b.last = now
+	}
+	if err == io.EOF {
+		if werr := b.save(); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.inner.Close()
+	if werr := b.save(); werr != nil {
+		return werr
+	}
+	return err
+}
+
+func (b *recordingBody) save() error {
+	b.once.Do(func() {
+		b.err = writeInteraction(b.path, b.interaction)
+		if b.err != nil {
+			b.err = fmt.Errorf("cannot record cassette: %w", b.err)
+		}
+	})
+	return b.err
+}
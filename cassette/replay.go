@@ -0,0 +1,119 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NoMatchError is returned by a Replayer for requests that were not
+// recorded.
+type NoMatchError struct {
+	Method string
+	URL    string
+	Dir    string
+}
+
+func (e *NoMatchError) Error() string {
+	return fmt.Sprintf("no recorded response in %s matches %s %s", e.Dir, e.Method, e.URL)
+}
+
+// Replayer is an http.RoundTripper that answers requests with the responses
+// recorded in Dir by a Recorder and never touches the network. Identical
+// requests are answered with their recorded responses in order.
+type Replayer struct {
+	Dir string
+	// Realtime replays the chunks of a response with their recorded delays.
+	Realtime bool
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewReplayer creates a Replayer for the cassettes in dir.
+func NewReplayer(dir string, realtime bool) (*Replayer, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open cassette directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("cassette directory %s is not a directory", dir)
+	}
+	return &Replayer{Dir: dir, Realtime: realtime}, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	url := requestURL(req)
+	key := Key(req.Method, url, body)
+
+	r.mu.Lock()
+	if r.counts == nil {
+		r.counts = map[string]int{}
+	}
+	n := r.counts[key]
+	r.counts[key]++
+	r.mu.Unlock()
+
+	interaction, err := readInteraction(path(r.Dir, key, n))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, &NoMatchError{Method: req.Method, URL: url, Dir: r.Dir}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        interaction.Response.Status,
+		StatusCode:    interaction.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.Response.Header.Clone(),
+		Body:          &replayBody{ctx: req.Context(), chunks: interaction.Response.Chunks, realtime: r.Realtime},
+		ContentLength: -1,
+		Request:       req,
+	}, nil
+}
+
+// replayBody plays back recorded chunks.
+type replayBody struct {
+	ctx      context.Context
+	chunks   []Chunk
+	realtime bool
+	current  *strings.Reader
+}
+
+func (b *replayBody) Read(p []byte) (int, error) {
+	for b.current == nil || b.current.Len() == 0 {
+		if len(b.chunks) == 0 {
+			return 0, io.EOF
+		}
+		chunk := b.chunks[0]
+		b.chunks = b.chunks[1:]
+
+		if b.realtime && chunk.Delay > 0 {
+			timer := time.NewTimer(chunk.Delay)
+			select {
+			case <-b.ctx.Done():
+				timer.Stop()
+				return 0, b.ctx.Err()
+			case <-timer.C:
+			}
+		}
+		b.current = strings.NewReader(chunk.Data)
+	}
+	return b.current.Read(p)
+}
+
+func (b *replayBody) Close() error { return nil }
@@ -25,6 +25,11 @@ func (o *OpenAIStreamer) ChatStream(ctx context.Context, request Input, onData f
 func (o *OpenAIStreamer) chatWithStream(ctx context.Context, request Input, onData func(message string) error) error {
 	// Prepare the OpenAI request parameters
 	params := createParams(request)
+	if wantsUsage(ctx) {
+		// usage is only requested when someone listens, which keeps the
+		// request the same as before otherwise
+		params.StreamOptions.IncludeUsage = param.NewOpt(true)
+	}
 
 	// Create the stream
 	stream := o.client.Chat.Completions.NewStreaming(ctx, params)
@@ -33,6 +38,9 @@ func (o *OpenAIStreamer) chatWithStream(ctx context.Context, request Input, onDa
 	// Process the stream
 	for stream.Next() {
 		chunk := stream.Current()
+		if chunk.Usage.TotalTokens > 0 {
+			ReportUsage(ctx, Usage{InputTokens: int(chunk.Usage.PromptTokens), OutputTokens: int(chunk.Usage.CompletionTokens)})
+		}
 
 		// Check if choices are available and extract content
 		if len(chunk.Choices) > 0 {
@@ -69,6 +77,9 @@ func (o *OpenAIStreamer) chatWithoutStream(ctx context.Context, request Input, o
 	if err != nil {
 		return err
 	}
+	if res.Usage.TotalTokens > 0 {
+		ReportUsage(ctx, Usage{InputTokens: int(res.Usage.PromptTokens), OutputTokens: int(res.Usage.CompletionTokens)})
+	}
 	return onData(res.Choices[0].Message.Content)
 }
 
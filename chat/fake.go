@@ -0,0 +1,139 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// FakeFailure selects the error a FakeStreamer injects.
+type FakeFailure string
+
+const (
+	// FakeRateLimit fails every request with a 429 before streaming.
+	FakeRateLimit FakeFailure = "rate_limit"
+	// FakeTruncate stops the stream halfway with io.ErrUnexpectedEOF.
+	FakeTruncate FakeFailure = "truncate"
+	// FakeTimeout stops sending halfway and waits until the context is
+	// done.
+	FakeTimeout FakeFailure = "timeout"
+)
+
+// FakeStatusError is the error a FakeStreamer returns for injected HTTP
+// failures.
+type FakeStatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *FakeStatusError) Error() string {
+	return fmt.Sprintf("fake provider: %d %s", e.StatusCode, e.Message)
+}
+
+// FakeStreamer is a Streamer that answers without any network access, for
+// testing and demos. It replies with Responses in order, starting over once
+// all of them were used, or echoes the last message if there are none.
+type FakeStreamer struct {
+	Responses []string
+	// Delay is the time to wait before each streamed token.
+	Delay time.Duration
+	// Failure is the error to inject into every request, if any.
+	Failure FakeFailure
+
+	mu    sync.Mutex
+	calls int
+}
+
+// ValidateFakeFailure checks that failure is a known FakeFailure. The empty
+// failure is valid and injects no error.
+func ValidateFakeFailure(failure FakeFailure) error {
+	switch failure {
+	case "", FakeRateLimit, FakeTruncate, FakeTimeout:
+		return nil
+	default:
+		return fmt.Errorf("invalid fake failure %q: expected %s, %s or %s", failure, FakeRateLimit, FakeTruncate, FakeTimeout)
+	}
+}
+
+func (f *FakeStreamer) response(request Input) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	defer func() { f.calls++ }()
+
+	if len(f.Responses) > 0 {
+		return f.Responses[f.calls%len(f.Responses)]
+	}
+	if len(request.Messages) == 0 {
+		return ""
+	}
+	return request.Messages[len(request.Messages)-1].Content
+}
+
+// fakeTokens splits text into tokens that each start with the whitespace in
+// front of a word, so that joining them results in text.
+func fakeTokens(text string) []string {
+	var tokens []string
+	start := 0
+	for i, r := range text {
+		if i > start && unicode.IsSpace(r) && !unicode.IsSpace(rune(text[i-1])) {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+func (f *FakeStreamer) ChatStream(ctx context.Context, request Input, onData func(message string) error) error {
+	if err := ValidateFakeFailure(f.Failure); err != nil {
+		return err
+	}
+	if f.Failure == FakeRateLimit {
+		return &FakeStatusError{StatusCode: 429, Message: "rate limit exceeded"}
+	}
+
+	tokens := fakeTokens(f.response(request))
+	for i, token := range tokens {
+		if f.Failure != "" && i >= len(tokens)/2 {
+			break
+		}
+
+		if f.Delay > 0 {
+			timer := time.NewTimer(f.Delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err := onData(token); err != nil {
+			return err
+		}
+	}
+
+	switch f.Failure {
+	case FakeTruncate:
+		return fmt.Errorf("fake provider: stream truncated: %w", io.ErrUnexpectedEOF)
+	case FakeTimeout:
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	// the fake provider counts its tokens like a real one would
+	usage := EstimateUsage(request.Messages, "")
+	ReportUsage(ctx, Usage{InputTokens: usage.InputTokens, OutputTokens: len(tokens)})
+	return nil
+}
+
+// ensure that FakeStreamer implements the Streamer interface
+var _ Streamer = (*FakeStreamer)(nil)
@@ -0,0 +1,96 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func collect(t *testing.T, streamer Streamer, ctx context.Context, input Input) ([]string, error) {
+	t.Helper()
+	var chunks []string
+	err := streamer.ChatStream(ctx, input, func(message string) error {
+		chunks = append(chunks, message)
+		return nil
+	})
+	return chunks, err
+}
+
+func TestFakeStreamer(t *testing.T) {
+	t.Parallel()
+
+	input := Input{Messages: []Message{{Role: "user", Content: "echo  this back\n"}}}
+
+	t.Run("echo", func(t *testing.T) {
+		t.Parallel()
+		chunks, err := collect(t, &FakeStreamer{}, context.Background(), input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{"echo", "  this", " back", "\n"}
+		if strings.Join(chunks, "|") != strings.Join(expected, "|") {
+			t.Errorf("Unexpected chunks.\nExpected: %#v\nActual:   %#v", expected, chunks)
+		}
+	})
+
+	t.Run("scripted responses", func(t *testing.T) {
+		t.Parallel()
+		streamer := &FakeStreamer{Responses: []string{"first", "second"}}
+		var replies []string
+		for i := 0; i < 3; i++ {
+			chunks, err := collect(t, streamer, context.Background(), input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			replies = append(replies, strings.Join(chunks, ""))
+		}
+		if strings.Join(replies, ",") != "first,second,first" {
+			t.Errorf("Unexpected replies: %#v", replies)
+		}
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		t.Parallel()
+		_, err := collect(t, &FakeStreamer{Failure: FakeRateLimit}, context.Background(), input)
+		var statusErr *FakeStatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != 429 {
+			t.Errorf("Expected a 429 FakeStatusError, got: %v", err)
+		}
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		t.Parallel()
+		chunks, err := collect(t, &FakeStreamer{Failure: FakeTruncate}, context.Background(), input)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("Expected io.ErrUnexpectedEOF, got: %v", err)
+		}
+		if len(chunks) != 2 {
+			t.Errorf("Expected half of the chunks, got: %#v", chunks)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := collect(t, &FakeStreamer{Failure: FakeTimeout}, ctx, input)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+		}
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		t.Parallel()
+		start := time.Now()
+		_, err := collect(t, &FakeStreamer{Delay: 5 * time.Millisecond}, context.Background(), input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+			t.Errorf("Expected the stream to take at least 20ms, took %s", elapsed)
+		}
+	})
+}
@@ -0,0 +1,310 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultGeminiEndpoint is the base url of the Google Gemini api.
+const DefaultGeminiEndpoint = "https://generativelanguage.googleapis.com/v1beta"
+
+// GeminiStreamer is a Streamer for the Google Gemini api. Assistant messages
+// are sent with the role "model" and system messages as the system
+// instruction.
+type GeminiStreamer struct {
+	// Endpoint is the base url of the api, DefaultGeminiEndpoint if empty.
+	Endpoint string
+	APIKey   string
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+	// Headers are sent with every request.
+	Headers map[string]string
+
+	disableStream bool
+}
+
+// NewGeminiStreamer creates a GeminiStreamer.
+func NewGeminiStreamer(endpoint, apiKey string, client *http.Client) *GeminiStreamer {
+	return &GeminiStreamer{Endpoint: endpoint, APIKey: apiKey, Client: client}
+}
+
+// ensure that GeminiStreamer implements the Streamer interface
+var _ Streamer = (*GeminiStreamer)(nil)
+
+// GeminiBlockedError is returned when Gemini blocks a prompt or a reply for
+// safety or policy reasons. Text streamed before a reply was blocked has
+// already been passed to onData.
+type GeminiBlockedError struct {
+	// Reason is the block reason of the prompt or the finish reason of the
+	// reply, such as "SAFETY".
+	Reason string
+	// Categories are the harm categories that caused the block.
+	Categories []string
+	// Prompt reports whether the prompt was blocked rather than the reply.
+	Prompt bool
+}
+
+func (e *GeminiBlockedError) Error() string {
+	what := "reply"
+	if e.Prompt {
+		what = "prompt"
+	}
+	msg := fmt.Sprintf("gemini blocked the %s: %s", what, e.Reason)
+	if len(e.Categories) > 0 {
+		msg += " (" + strings.Join(e.Categories, ", ") + ")"
+	}
+	return msg
+}
+
+// GeminiStatusError is returned for requests the Gemini api rejects.
+type GeminiStatusError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *GeminiStatusError) Error() string {
+	return fmt.Sprintf("gemini: %d %s: %s", e.StatusCode, e.Status, e.Message)
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	Temperature     *float32 `json:"temperature,omitempty"`
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent         `json:"contents"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content       geminiContent        `json:"content"`
+		FinishReason  string               `json:"finishReason"`
+		SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason   string               `json:"blockReason"`
+		SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
+	} `json:"promptFeedback"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// geminiBlockReasons are the finish reasons of replies that were blocked.
+var geminiBlockReasons = map[string]bool{
+	"SAFETY":             true,
+	"RECITATION":         true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
+}
+
+func (g *GeminiStreamer) ChatStream(ctx context.Context, request Input, onData func(message string) error) error {
+	method := "streamGenerateContent?alt=sse"
+	if g.disableStream {
+		method = "generateContent"
+	}
+	resp, err := g.post(ctx, request, method)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if g.disableStream {
+		var response geminiResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			return fmt.Errorf("gemini: invalid response: %w", err)
+		}
+		response.reportUsage(ctx)
+		return response.emit(onData)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var response geminiResponse
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &response); err != nil {
+			return fmt.Errorf("gemini: invalid response: %w", err)
+		}
+		// every chunk carries the usage so far
+		response.reportUsage(ctx)
+		if err := response.emit(onData); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// post sends the request to the method of the request's model.
+func (g *GeminiStreamer) post(ctx context.Context, request Input, method string) (*http.Response, error) {
+	body, err := json.Marshal(geminiParams(request))
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := g.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultGeminiEndpoint
+	}
+	model := strings.TrimPrefix(request.Model, "models/")
+	url := strings.TrimSuffix(endpoint, "/") + "/models/" + model + ":" + method
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.APIKey != "" {
+		req.Header.Set("X-Goog-Api-Key", g.APIKey)
+	}
+	for name, value := range g.Headers {
+		req.Header.Set(name, value)
+	}
+
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, geminiStatusError(resp)
+	}
+	return resp, nil
+}
+
+// geminiStatusError reads the error of a rejected request.
+func geminiStatusError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+		} `json:"error"`
+	}
+	statusErr := &GeminiStatusError{StatusCode: resp.StatusCode, Status: http.StatusText(resp.StatusCode), Message: strings.TrimSpace(string(data))}
+	if json.Unmarshal(data, &body) == nil && body.Error.Message != "" {
+		statusErr.Message = body.Error.Message
+		if body.Error.Status != "" {
+			statusErr.Status = body.Error.Status
+		}
+	}
+	return statusErr
+}
+
+// emit passes the text of the response to onData, and reports blocked
+// prompts and replies.
+func (r *geminiResponse) emit(onData func(message string) error) error {
+	if r.PromptFeedback != nil && r.PromptFeedback.BlockReason != "" {
+		return &GeminiBlockedError{
+			Reason:     r.PromptFeedback.BlockReason,
+			Categories: blockedCategories(r.PromptFeedback.SafetyRatings),
+			Prompt:     true,
+		}
+	}
+	if len(r.Candidates) == 0 {
+		return nil
+	}
+
+	candidate := r.Candidates[0]
+	for _, part := range candidate.Content.Parts {
+		if part.Text == "" {
+			continue
+		}
+		if err := onData(part.Text); err != nil {
+			return err
+		}
+	}
+	if geminiBlockReasons[candidate.FinishReason] {
+		return &GeminiBlockedError{
+			Reason:     candidate.FinishReason,
+			Categories: blockedCategories(candidate.SafetyRatings),
+		}
+	}
+	return nil
+}
+
+// reportUsage reports the usage in the response, if it has any.
+func (r *geminiResponse) reportUsage(ctx context.Context) {
+	if r.UsageMetadata != nil {
+		ReportUsage(ctx, Usage{InputTokens: r.UsageMetadata.PromptTokenCount, OutputTokens: r.UsageMetadata.CandidatesTokenCount})
+	}
+}
+
+// blockedCategories returns the categories of the ratings that blocked a
+// prompt or reply.
+func blockedCategories(ratings []geminiSafetyRating) []string {
+	var categories []string
+	for _, rating := range ratings {
+		if rating.Blocked || rating.Probability == "HIGH" {
+			categories = append(categories, rating.Category)
+		}
+	}
+	return categories
+}
+
+// geminiParams maps the request to the Gemini api. Consecutive messages of
+// the same role are merged, since Gemini expects the roles to alternate.
+func geminiParams(request Input) geminiRequest {
+	var params geminiRequest
+	for _, msg := range request.Messages {
+		part := geminiPart{Text: msg.Content}
+		if msg.Role == "system" {
+			if params.SystemInstruction == nil {
+				params.SystemInstruction = &geminiContent{}
+			}
+			params.SystemInstruction.Parts = append(params.SystemInstruction.Parts, part)
+			continue
+		}
+
+		role := "user"
+		if msg.Role == "assistant" {
+			role = "model"
+		}
+		if n := len(params.Contents); n > 0 && params.Contents[n-1].Role == role {
+			params.Contents[n-1].Parts = append(params.Contents[n-1].Parts, part)
+			continue
+		}
+		params.Contents = append(params.Contents, geminiContent{Role: role, Parts: []geminiPart{part}})
+	}
+
+	if request.MaxTokens > 0 || request.Temperature != nil {
+		params.GenerationConfig = &geminiGenerationConfig{
+			MaxOutputTokens: request.MaxTokens,
+			Temperature:     request.Temperature,
+		}
+	}
+	return params
+}
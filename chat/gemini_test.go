@@ -0,0 +1,150 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yiblet/hlp/cassette"
+)
+
+// newGeminiReplayStreamer returns a GeminiStreamer that is answered from the
+// cassettes recorded in testdata/gemini.
+func newGeminiReplayStreamer(t *testing.T) *GeminiStreamer {
+	t.Helper()
+	replayer, err := cassette.NewReplayer("testdata/gemini", false)
+	if err != nil {
+		t.Fatalf("cannot open cassettes: %v", err)
+	}
+	return NewGeminiStreamer("http://gemini.test/v1beta", "test-key", &http.Client{Transport: replayer})
+}
+
+func TestGeminiStreamer_ChatStream(t *testing.T) {
+	t.Parallel()
+
+	t.Run("stream", testGeminiStream)
+	t.Run("without stream", testGeminiWithoutStream)
+	t.Run("blocked prompt", testGeminiBlockedPrompt)
+	t.Run("blocked reply", testGeminiBlockedReply)
+	t.Run("status error", testGeminiStatusError)
+}
+
+func testGeminiStream(t *testing.T) {
+	t.Parallel()
+	temperature := float32(0.5)
+	chunks, err := collect(t, newGeminiReplayStreamer(t), context.Background(), Input{
+		Model:       "gemini-1.5-flash",
+		MaxTokens:   64,
+		Temperature: &temperature,
+		Messages: []Message{
+			{Role: "system", Content: "You are terse."},
+			{Role: "user", Content: "Hi"},
+			{Role: "assistant", Content: "Hello."},
+			{Role: "user", Content: "Say hello"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ChatStream returned an unexpected error: %v", err)
+	}
+
+	expected := []string{"Hello", " from", " Gemini"}
+	if strings.Join(chunks, "|") != strings.Join(expected, "|") {
+		t.Errorf("Unexpected stream chunks.\nExpected: %#v\nActual:   %#v", expected, chunks)
+	}
+}
+
+func testGeminiWithoutStream(t *testing.T) {
+	t.Parallel()
+	streamer := newGeminiReplayStreamer(t)
+	streamer.disableStream = true
+
+	chunks, err := collect(t, streamer, context.Background(), Input{
+		Model:    "models/gemini-1.5-flash",
+		Messages: []Message{{Role: "user", Content: "Say hello"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatStream returned an unexpected error: %v", err)
+	}
+	if strings.Join(chunks, "") != "Hello without streaming" {
+		t.Errorf("Unexpected response: %#v", chunks)
+	}
+}
+
+func testGeminiBlockedPrompt(t *testing.T) {
+	t.Parallel()
+	chunks, err := collect(t, newGeminiReplayStreamer(t), context.Background(), Input{
+		Model:    "gemini-1.5-flash",
+		Messages: []Message{{Role: "user", Content: "forbidden prompt"}},
+	})
+
+	var blocked *GeminiBlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("Expected a GeminiBlockedError, got: %v", err)
+	}
+	if !blocked.Prompt || blocked.Reason != "SAFETY" {
+		t.Errorf("Unexpected block: %#v", blocked)
+	}
+	if strings.Join(blocked.Categories, ",") != "HARM_CATEGORY_DANGEROUS_CONTENT" {
+		t.Errorf("Unexpected categories: %#v", blocked.Categories)
+	}
+	if len(chunks) != 0 {
+		t.Errorf("Unexpected chunks: %#v", chunks)
+	}
+}
+
+func testGeminiBlockedReply(t *testing.T) {
+	t.Parallel()
+	chunks, err := collect(t, newGeminiReplayStreamer(t), context.Background(), Input{
+		Model:    "gemini-1.5-flash",
+		Messages: []Message{{Role: "user", Content: "forbidden reply"}},
+	})
+
+	var blocked *GeminiBlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("Expected a GeminiBlockedError, got: %v", err)
+	}
+	if blocked.Prompt || blocked.Reason != "SAFETY" {
+		t.Errorf("Unexpected block: %#v", blocked)
+	}
+	if strings.Join(chunks, "") != "Here is" {
+		t.Errorf("Expected the text before the block, got: %#v", chunks)
+	}
+}
+
+func testGeminiStatusError(t *testing.T) {
+	t.Parallel()
+	_, err := collect(t, newGeminiReplayStreamer(t), context.Background(), Input{
+		Model:    "gemini-1.5-flash",
+		Messages: []Message{{Role: "user", Content: "bad key"}},
+	})
+
+	var statusErr *GeminiStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("Expected a GeminiStatusError, got: %v", err)
+	}
+	if statusErr.StatusCode != 400 || statusErr.Status != "INVALID_ARGUMENT" {
+		t.Errorf("Unexpected status error: %#v", statusErr)
+	}
+}
+
+func TestGeminiParams(t *testing.T) {
+	params := geminiParams(Input{Messages: []Message{
+		{Role: "system", Content: "a"},
+		{Role: "user", Content: "b"},
+		{Role: "user", Content: "c"},
+		{Role: "assistant", Content: "d"},
+		{Role: "system", Content: "e"},
+	}})
+
+	if params.SystemInstruction == nil || len(params.SystemInstruction.Parts) != 2 {
+		t.Fatalf("Expected both system messages in the system instruction: %#v", params.SystemInstruction)
+	}
+	if len(params.Contents) != 2 || params.Contents[0].Role != "user" || len(params.Contents[0].Parts) != 2 || params.Contents[1].Role != "model" {
+		t.Errorf("Unexpected contents: %#v", params.Contents)
+	}
+	if params.GenerationConfig != nil {
+		t.Errorf("Unexpected generation config: %#v", params.GenerationConfig)
+	}
+}
@@ -0,0 +1,84 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Middleware wraps a Streamer to add behavior such as logging, caching or
+// auditing around its ChatStream calls.
+type Middleware func(Streamer) Streamer
+
+// StreamerFunc adapts a function to the Streamer interface.
+type StreamerFunc func(ctx context.Context, request Input, onData func(message string) error) error
+
+func (f StreamerFunc) ChatStream(ctx context.Context, request Input, onData func(message string) error) error {
+	return f(ctx, request, onData)
+}
+
+// Chain wraps streamer in the given middlewares. The first middleware is the
+// outermost one: it sees a request first and its reply last.
+func Chain(streamer Streamer, middlewares ...Middleware) Streamer {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		streamer = middlewares[i](streamer)
+	}
+	return streamer
+}
+
+// Timeout returns a Middleware that wraps a Streamer in a TimeoutStreamer.
+func Timeout(timeout, idleTimeout time.Duration) Middleware {
+	return func(next Streamer) Streamer {
+		return &TimeoutStreamer{Streamer: next, Timeout: timeout, IdleTimeout: idleTimeout}
+	}
+}
+
+// UnknownMiddlewareError is returned when looking up a middleware name that
+// was never registered.
+type UnknownMiddlewareError struct {
+	Name string
+}
+
+func (e *UnknownMiddlewareError) Error() string {
+	return fmt.Sprintf("unknown middleware %q", e.Name)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Middleware{}
+)
+
+// RegisterMiddleware makes a middleware available under name, so that it can
+// be enabled from the configuration. Registering a name twice replaces the
+// earlier middleware. Programs embedding hlp usually call it from an init
+// function.
+func RegisterMiddleware(name string, middleware Middleware) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = middleware
+}
+
+// LookupMiddleware returns the middleware registered under name.
+func LookupMiddleware(name string) (Middleware, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	middleware, ok := registry[name]
+	if !ok {
+		return nil, &UnknownMiddlewareError{Name: name}
+	}
+	return middleware, nil
+}
+
+// Middlewares returns the names of the registered middlewares in sorted order.
+func Middlewares() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
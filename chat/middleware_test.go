@@ -0,0 +1,61 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tagMiddleware(tag string, calls *[]string) Middleware {
+	return func(next Streamer) Streamer {
+		return StreamerFunc(func(ctx context.Context, request Input, onData func(string) error) error {
+			*calls = append(*calls, tag+" before")
+			err := next.ChatStream(ctx, request, onData)
+			*calls = append(*calls, tag+" after")
+			return err
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	base := StreamerFunc(func(ctx context.Context, request Input, onData func(string) error) error {
+		calls = append(calls, "streamer")
+		return onData("hello")
+	})
+
+	streamer := Chain(base, tagMiddleware("outer", &calls), tagMiddleware("inner", &calls))
+
+	var out strings.Builder
+	err := streamer.ChatStream(context.Background(), Input{}, func(message string) error {
+		out.WriteString(message)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", out.String())
+	assert.Equal(t, []string{"outer before", "inner before", "streamer", "inner after", "outer after"}, calls)
+}
+
+func TestChainWithoutMiddlewares(t *testing.T) {
+	base := &FakeStreamer{}
+	assert.Same(t, Streamer(base), Chain(base))
+}
+
+func TestLookupMiddleware(t *testing.T) {
+	var calls []string
+	RegisterMiddleware("test-tag", tagMiddleware("tag", &calls))
+
+	middleware, err := LookupMiddleware("test-tag")
+	require.NoError(t, err)
+	assert.NotNil(t, middleware)
+	assert.Contains(t, Middlewares(), "test-tag")
+
+	_, err = LookupMiddleware("missing")
+	var unknown *UnknownMiddlewareError
+	require.True(t, errors.As(err, &unknown))
+	assert.Equal(t, "missing", unknown.Name)
+}
@@ -0,0 +1,102 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/yiblet/hlp/cassette"
+)
+
+// newReplayStreamer returns an OpenAIStreamer that is answered from the
+// cassettes recorded in testdata/openai.
+func newReplayStreamer(t *testing.T) *OpenAIStreamer {
+	t.Helper()
+	replayer, err := cassette.NewReplayer("testdata/openai", false)
+	if err != nil {
+		t.Fatalf("cannot open cassettes: %v", err)
+	}
+	client := openai.NewClient(
+		option.WithBaseURL("http://openai.test/v1/"),
+		option.WithAPIKey("test-key"),
+		option.WithMaxRetries(0),
+		option.WithHTTPClient(&http.Client{Transport: replayer}),
+	)
+	return NewOpenAIStreamer(client)
+}
+
+func TestOpenAIStreamer_ChatStream(t *testing.T) {
+	t.Parallel()
+
+	t.Run("stream", testOpenAIStream)
+	t.Run("without stream", testOpenAIWithoutStream)
+	t.Run("unrecorded request", testOpenAIUnrecorded)
+}
+
+func testOpenAIStream(t *testing.T) {
+	t.Parallel()
+	streamer := newReplayStreamer(t)
+
+	var chunks []string
+	err := streamer.ChatStream(context.Background(), Input{
+		Model: "gpt-4o-mini",
+		Messages: []Message{
+			{Role: "system", Content: "You are terse."},
+			{Role: "user", Content: "Say hello"},
+		},
+	}, func(message string) error {
+		chunks = append(chunks, message)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatStream returned an unexpected error: %v", err)
+	}
+
+	expected := []string{"Hello", " from", " the", " cassette"}
+	if strings.Join(chunks, "|") != strings.Join(expected, "|") {
+		t.Errorf("Unexpected stream chunks.\nExpected: %#v\nActual:   %#v", expected, chunks)
+	}
+}
+
+func testOpenAIWithoutStream(t *testing.T) {
+	t.Parallel()
+	streamer := newReplayStreamer(t)
+	streamer.disableStream = true
+
+	var sb strings.Builder
+	err := streamer.ChatStream(context.Background(), Input{
+		Model:    "gpt-4o-mini",
+		Messages: []Message{{Role: "user", Content: "Say hello"}},
+	}, func(message string) error {
+		sb.WriteString(message)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatStream returned an unexpected error: %v", err)
+	}
+	if sb.String() != "Hello without streaming" {
+		t.Errorf("Unexpected response: %#v", sb.String())
+	}
+}
+
+func testOpenAIUnrecorded(t *testing.T) {
+	t.Parallel()
+	streamer := newReplayStreamer(t)
+
+	err := streamer.ChatStream(context.Background(), Input{
+		Model:    "gpt-4o-mini",
+		Messages: []Message{{Role: "user", Content: "This was never recorded"}},
+	}, func(message string) error {
+		t.Errorf("Unexpected message: %#v", message)
+		return nil
+	})
+
+	var noMatch *cassette.NoMatchError
+	if !errors.As(err, &noMatch) {
+		t.Errorf("Expected a NoMatchError, got: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// IdleTimeoutError is returned when a stream does not receive any data for
+// longer than its idle timeout.
+type IdleTimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *IdleTimeoutError) Error() string {
+	return fmt.Sprintf("stream idle timeout: no data received for %s", e.Timeout)
+}
+
+// TimeoutStreamer limits how long the ChatStream calls of the wrapped
+// Streamer may take. A zero duration disables the respective limit.
+type TimeoutStreamer struct {
+	Streamer Streamer
+	// Timeout limits the total duration of a ChatStream call.
+	Timeout time.Duration
+	// IdleTimeout limits the time between the start of a ChatStream call and
+	// its first message, and between two consecutive messages.
+	IdleTimeout time.Duration
+}
+
+func (t *TimeoutStreamer) ChatStream(ctx context.Context, request Input, onData func(message string) error) error {
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+	if t.IdleTimeout <= 0 {
+		return t.Streamer.ChatStream(ctx, request, onData)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	idleErr := &IdleTimeoutError{Timeout: t.IdleTimeout}
+	timer := time.AfterFunc(t.IdleTimeout, func() { cancel(idleErr) })
+	defer timer.Stop()
+
+	err := t.Streamer.ChatStream(ctx, request, func(message string) error {
+		timer.Reset(t.IdleTimeout)
+		return onData(message)
+	})
+	if err != nil && context.Cause(ctx) == idleErr {
+		return idleErr
+	}
+	return err
+}
+
+// ensure that TimeoutStreamer implements the Streamer interface
+var _ Streamer = (*TimeoutStreamer)(nil)
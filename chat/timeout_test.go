@@ -0,0 +1,91 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// slowStream sends its messages with a delay before each one.
+type slowStream struct {
+	messages []string
+	delay    time.Duration
+}
+
+func (s *slowStream) ChatStream(ctx context.Context, request Input, onData func(message string) error) error {
+	for _, message := range s.messages {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.delay):
+		}
+		if err := onData(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestTimeoutStreamer(t *testing.T) {
+	t.Parallel()
+
+	messages := []string{"a", "b", "c", "d"}
+	testCases := []struct {
+		name        string
+		timeout     time.Duration
+		idleTimeout time.Duration
+		check       func(t *testing.T, err error)
+	}{
+		{
+			name: "no timeouts",
+			check: func(t *testing.T, err error) {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name:        "messages within idle timeout",
+			idleTimeout: 200 * time.Millisecond,
+			check: func(t *testing.T, err error) {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name:        "idle timeout",
+			idleTimeout: 5 * time.Millisecond,
+			check: func(t *testing.T, err error) {
+				var idleErr *IdleTimeoutError
+				if !errors.As(err, &idleErr) {
+					t.Errorf("expected an IdleTimeoutError, got: %v", err)
+				}
+			},
+		},
+		{
+			name:    "total timeout",
+			timeout: 30 * time.Millisecond,
+			check: func(t *testing.T, err error) {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("expected a deadline exceeded error, got: %v", err)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			streamer := &TimeoutStreamer{
+				Streamer:    &slowStream{messages: messages, delay: 20 * time.Millisecond},
+				Timeout:     tc.timeout,
+				IdleTimeout: tc.idleTimeout,
+			}
+			err := streamer.ChatStream(context.Background(), Input{}, func(string) error { return nil })
+			tc.check(t, err)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package chat
+
+import (
+	"context"
+	"unicode/utf8"
+)
+
+// Usage is the number of tokens a request used.
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	// Estimated reports that the counts were estimated from the text since
+	// the provider did not report them, see EstimateUsage.
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+type usageKey struct{}
+
+// WithUsage returns a context whose requests report the tokens they used to
+// report. Streamers that learn the usage of a request call ReportUsage, and
+// may ask the provider for it only if someone is listening, so report may
+// never be called.
+func WithUsage(ctx context.Context, report func(Usage)) context.Context {
+	return context.WithValue(ctx, usageKey{}, report)
+}
+
+// ReportUsage reports the usage of the request made with ctx, if the context
+// was created by WithUsage.
+func ReportUsage(ctx context.Context, usage Usage) {
+	if report, ok := ctx.Value(usageKey{}).(func(Usage)); ok {
+		report(usage)
+	}
+}
+
+// wantsUsage reports whether the usage of requests made with ctx is
+// reported to anyone.
+func wantsUsage(ctx context.Context) bool {
+	_, ok := ctx.Value(usageKey{}).(func(Usage))
+	return ok
+}
+
+// EstimateUsage estimates the usage of a request from its messages and reply
+// at about four characters per token.
+func EstimateUsage(messages []Message, reply string) Usage {
+	var input int
+	for _, msg := range messages {
+		input += estimateTokens(msg.Content)
+	}
+	return Usage{InputTokens: input, OutputTokens: estimateTokens(reply), Estimated: true}
+}
+
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
@@ -1,99 +1,93 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
-	"time"
 
+	"github.com/yiblet/hlp/cache"
 	"github.com/yiblet/hlp/chat"
 	"github.com/yiblet/hlp/parse"
+	"github.com/yiblet/hlp/profile"
+	"github.com/yiblet/hlp/session"
 )
 
 type chatCmd struct {
-	File        string   `arg:"required,positional" help:"the input chat file, if you pass - the command will read from stdin"`
-	Write       *string  `arg:"positional" help:"the output chat file, if you pass - the output will be the same as input"`
-	MaxTokens   int      `arg:"--tokens,-t" default:"0" help:"the maximum amount of tokens allowed in the output"`
-	Temperature *float32 `-arg:"--temp"`
-	Color       bool     `default:"false"`
-	Model       string   `arg:"--model,-m" help:"set openai model"`
+	File         string   `arg:"required,positional" help:"the input chat file, if you pass - the command will read from stdin"`
+	Write        *string  `arg:"positional" help:"the output chat file, if you pass - the output will be the same as input"`
+	MaxTokens    int      `arg:"--tokens,-t" default:"0" help:"the maximum amount of tokens allowed in the output"`
+	Temperature  *float32 `-arg:"--temp"`
+	Color        bool     `default:"false"`
+	Model        string   `arg:"--model,-m" help:"set openai model"`
+	Regenerate   bool     `arg:"--regenerate,-r" help:"replace the last assistant reply instead of appending a new one"`
+	Count        int      `arg:"-n" default:"1" help:"the number of alternative replies to generate"`
+	Pick         int      `arg:"--pick" help:"keep alternative N of the replies at the end of the chat file, drop the others and exit"`
+	Watch        bool     `arg:"--watch,-w" help:"watch the input file and answer every saved user turn in place"`
+	Edit         bool     `arg:"--edit,-e" help:"chat interactively by editing the input file in $EDITOR"`
+	Backup       bool     `arg:"--backup" help:"keep the previous version of the output file as <file>.bak"`
+	StreamToFile bool     `arg:"--stream-to-file" help:"append the reply to the output file as it arrives"`
+	Resume       bool     `arg:"--resume" help:"continue the partial reply at the end of the chat file"`
+	templateArgs
 }
 
-// appendChatFile appends a new chat response to the specified file.
-// The role parameter must be one of "system", "assistant", or "user".
-// The content parameter should contain the text of the chat response.
-// The file will be created if it doesn't exist, and the new chat response
-// will be appended to the existing content in the file.
-//
-// The format of the appended chat response will be:
-//
-// --- role
-// content
-//
-// The function returns an error if the role is invalid or if there's an issue
-// while opening or writing to the file.
-func (args *chatCmd) appendChatFile(writer io.Writer, role, content string) error {
-	if err := parse.ValidateRole(role); err != nil {
-		return err
+// outputFile returns the path of the output chat file, or an empty string if
+// the output should not be written.
+func (args *chatCmd) outputFile() (string, error) {
+	if args.Write == nil {
+		return "", nil
 	}
 
-	buf := bufio.NewWriter(writer)
-	if _, err := fmt.Fprintf(buf, "--- %s\n%s\n", role, content); err != nil {
-		return err
+	outfile := *args.Write
+	if outfile == "-" {
+		if args.File == "-" {
+			return "", fmt.Errorf("cannot output to stdin")
+		}
+		outfile = args.File
 	}
-	return buf.Flush()
+	return outfile, nil
 }
 
-func (args *chatCmd) writeTo(
+func (args *chatCmd) write(
 	input string,
-	content string,
-	writer io.Writer,
+	contents []string,
 ) error {
-	// generate output for writing
-	output := bufio.NewWriter(writer)
-	if _, err := output.WriteString(input); err != nil {
+	outfile, err := args.outputFile()
+	if err != nil || outfile == "" {
 		return err
 	}
 
-	output.WriteRune('\n')
-	if input[len(input)-1] != '\n' { // add an extra line if needed
-		output.WriteRune('\n')
-	}
-	if err := args.appendChatFile(output, "assistant", content); err != nil {
-		return err
-	}
-
-	return output.Flush()
+	return args.save(outfile, func(writer io.Writer) error {
+		return session.WriteChat(writer, input, contents)
+	})
 }
 
-func (args *chatCmd) write(
-	input string,
-	content string,
-) error {
-	if args.Write == nil {
-		return nil
-	}
+// save atomically replaces the chat file at path with the output of write,
+// backing up the previous version first if requested.
+func (args *chatCmd) save(path string, write func(io.Writer) error) error {
+	return saveFile(path, args.Backup, write)
+}
 
-	outfile := *args.Write
+// saveString is like save but writes content.
+func (args *chatCmd) saveString(path string, content string) error {
+	return args.save(path, func(writer io.Writer) error {
+		_, err := io.WriteString(writer, content)
+		return err
+	})
+}
 
-	if outfile == "-" {
-		if args.File == "-" {
-			return fmt.Errorf("cannot output to stdin")
+// saveFile atomically replaces the file at path with the output of write. If
+// backup is set, the previous version is kept as <path>.bak.
+func saveFile(path string, backup bool, write func(io.Writer) error) error {
+	if backup {
+		err := copyFile(path, path+".bak")
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("cannot back up %s: %w", path, err)
 		}
-		outfile = args.File
-	}
-
-	file, err := os.OpenFile(outfile, os.O_WRONLY|os.O_CREATE, 0644)
-	if err != nil {
-		return err
 	}
-
-	defer file.Close()
-	return args.writeTo(input, content, file)
+	return writeFileAtomic(path, write)
 }
 
 func (args *chatCmd) outputWriter() (io.Writer, func() error) {
@@ -108,24 +102,41 @@ func (args *chatCmd) outputWriter() (io.Writer, func() error) {
 	return outputWriter, close
 }
 
-func (args *chatCmd) readAll(reader io.Reader) error {
-	var buf [4096]byte
-	for {
-		_, err := reader.Read(buf[:])
-		if err != nil {
-			return err
-		}
+func (args *chatCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	if args.Pick != 0 {
+		return args.pick()
 	}
-}
 
-func (args *chatCmd) Execute(ctx context.Context, config *config) error {
 	model := args.Model
 	if model == "" {
 		model = strings.TrimSpace(config.Model())
 	}
 
-	var err error
-	client := config.Client()
+	client, err := config.Client()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case args.Watch:
+		return args.watch(ctx, client, model)
+	case args.Edit:
+		return args.edit(ctx, client, model)
+	}
+
+	// hold the lock on the output file from reading the input until the
+	// reply is written so concurrent runs cannot overwrite each other
+	outfile, err := args.outputFile()
+	if err != nil {
+		return err
+	}
+	if outfile != "" {
+		unlock, err := lockFile(outfile)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
 
 	var file io.ReadCloser
 	if args.File != "-" {
@@ -138,39 +149,208 @@ func (args *chatCmd) Execute(ctx context.Context, config *config) error {
 		file = os.Stdin
 	}
 
-	var inputContent strings.Builder
-	reader := io.TeeReader(file, &inputContent)
-
-	// Read and parse the file
-	messages, err := parse.ParseChatFile(reader)
+	buf, err := io.ReadAll(file)
 	if err != nil {
 		return err
 	}
-	if err := args.readAll(reader); err != nil && !errors.Is(err, io.EOF) {
-		return err
-	}
+	input := string(buf)
 
-	var outputContent strings.Builder
 	outputWriter, closeWriter := args.outputWriter()
 	defer closeWriter()
 
-	writer := io.MultiWriter(&outputContent, outputWriter)
+	if args.StreamToFile {
+		if outfile == "" {
+			return fmt.Errorf("--stream-to-file needs an output file")
+		}
+		return args.streamToFile(ctx, client, model, input, outfile, outputWriter)
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, time.Minute*2)
-	defer cancel()
-	// Call ChatCompletionStream with the parsed messages
-	err = client.ChatStream(ctx, chat.Input{
-		Messages:    messages,
-		MaxTokens:   args.MaxTokens,
-		Temperature: args.Temperature,
-		Model:       model,
-	}, func(message string) error {
-		fmt.Fprint(writer, message)
-		return nil
+	input, contents, err := args.respond(ctx, client, model, input, outputWriter)
+	if errors.Is(err, errInterrupted) {
+		fmt.Fprintln(outputWriter)
+		return args.writeInterrupted(input, contents)
+	}
+	if err != nil {
+		return err
+	}
+
+	return args.write(input, contents)
+}
+
+// writeInterrupted keeps a single interrupted reply in the output file,
+// marked as partial so that it can be resumed or regenerated.
+func (args *chatCmd) writeInterrupted(input string, contents []string) error {
+	outfile, err := args.outputFile()
+	if err != nil {
+		return err
+	}
+	if outfile == "" {
+		return errInterrupted
+	}
+	if len(contents) > 1 {
+		return fmt.Errorf("%w: alternative replies were not saved", errInterrupted)
+	}
+
+	var output strings.Builder
+	if err := session.WriteChat(&output, input, nil); err != nil {
+		return err
+	}
+	fmt.Fprintf(&output, "%s\n%s\n", parse.FormatPartialBoundary("assistant"), contents[0])
+	if err := args.saveString(outfile, output.String()); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: the partial reply is saved in %s", errInterrupted, outfile)
+}
+
+// prepare parses the chat file input into the request for its next reply.
+func (args *chatCmd) prepare(input string) (session.ChatFile, error) {
+	return session.PrepareChat(input, session.ChatOptions{
+		Regenerate: args.Regenerate,
+		Resume:     args.Resume,
+		// the template is rendered after parsing so the chat file itself is
+		// written back untouched
+		Render: args.renderMessages,
 	})
+}
+
+// respond generates the replies to the chat file input and streams them into
+// outputWriter. It returns the input the replies should be appended to, which
+// differs from the original input when regenerating or resuming. If the
+// generation is interrupted, the last content is the partial reply and the
+// error is errInterrupted.
+func (args *chatCmd) respond(
+	ctx context.Context,
+	client chat.Streamer,
+	model string,
+	input string,
+	outputWriter io.Writer,
+) (string, []string, error) {
+	request, err := args.prepare(input)
+	if err != nil {
+		return "", nil, err
+	}
+
+	count := max(args.Count, 1)
+	if args.Regenerate || count > 1 {
+		// a cached reply would not be a new reply
+		ctx = cache.SkipLookup(ctx)
+	}
+
+	contents := make([]string, 0, count)
+	for idx := 1; idx <= count; idx++ {
+		if count > 1 {
+			fmt.Fprintf(outputWriter, "%s\n", parse.FormatBoundary("assistant", idx, count))
+		}
+
+		fmt.Fprint(outputWriter, request.Prefix)
+		content, err := args.generate(ctx, client, model, request.Messages, outputWriter)
+		if errors.Is(err, errInterrupted) {
+			// the partial reply is returned so the caller can keep it
+			return request.Input, append(contents, request.Prefix+content), err
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		content = request.Prefix + content
+		contents = append(contents, content)
+
+		if count > 1 && !strings.HasSuffix(content, "\n") {
+			fmt.Fprintln(outputWriter)
+		}
+	}
+
+	return request.Input, contents, nil
+}
+
+// streamToFile generates a single reply to the chat file input and appends
+// it to outfile as it arrives. The reply is marked as partial until it is
+// complete, so an interrupted reply can be told apart and resumed or
+// regenerated later.
+func (args *chatCmd) streamToFile(
+	ctx context.Context,
+	client chat.Streamer,
+	model string,
+	input string,
+	outfile string,
+	outputWriter io.Writer,
+) error {
+	if args.Count > 1 {
+		return fmt.Errorf("cannot stream alternative replies to a file")
+	}
+
+	request, err := args.prepare(input)
 	if err != nil {
 		return err
 	}
 
-	return args.write(inputContent.String(), outputContent.String())
+	var header strings.Builder
+	if err := session.WriteChat(&header, request.Input, nil); err != nil {
+		return err
+	}
+	offset := header.Len()
+	fmt.Fprintf(&header, "%s\n%s", parse.FormatPartialBoundary("assistant"), request.Prefix)
+	// the file is written twice, the backup is of the version before the
+	// first write
+	if err := args.saveString(outfile, header.String()); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(outfile, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fmt.Fprint(outputWriter, request.Prefix)
+	_, err = args.generate(ctx, client, model, request.Messages, io.MultiWriter(file, outputWriter))
+	if _, werr := file.WriteString("\n"); err == nil {
+		err = werr
+	}
+	if err != nil {
+		return fmt.Errorf("reply was interrupted and is marked as partial in %s: %w", outfile, err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	// the reply is complete, replace the partial boundary with a regular one
+	buf, err := os.ReadFile(outfile)
+	if err != nil {
+		return err
+	}
+	rest := strings.TrimPrefix(string(buf[offset:]), parse.FormatPartialBoundary("assistant"))
+	return saveFile(outfile, false, func(writer io.Writer) error {
+		_, err := io.WriteString(writer, string(buf[:offset])+parse.FormatBoundary("assistant", 1, 1)+rest)
+		return err
+	})
+}
+
+// generate streams a single reply to messages into outputWriter and returns
+// it. The generation can be canceled with Ctrl-C, in which case the partial
+// reply is returned together with errInterrupted.
+func (args *chatCmd) generate(
+	ctx context.Context,
+	client chat.Streamer,
+	model string,
+	messages []chat.Message,
+	outputWriter io.Writer,
+) (string, error) {
+	conversation := &session.Conversation{
+		Streamer:    client,
+		Model:       model,
+		MaxTokens:   args.MaxTokens,
+		Temperature: args.Temperature,
+		Messages:    messages,
+	}
+
+	var content string
+	err := interrupts.run(ctx, func(ctx context.Context) error {
+		var err error
+		content, err = conversation.Generate(ctx, func(message string) error {
+			_, err := fmt.Fprint(outputWriter, message)
+			return err
+		})
+		return err
+	})
+	return content, err
 }
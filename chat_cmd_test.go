@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/parse"
+	"github.com/yiblet/hlp/profile"
+)
+
+func writeChatFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.chat")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func readChatFile(t *testing.T, path string) string {
+	t.Helper()
+	buf, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(buf)
+}
+
+func TestChatCmd(t *testing.T) {
+	inPlace := "-"
+
+	t.Run("append reply", func(t *testing.T) {
+		path := writeChatFile(t, "--- user\nhello\n")
+		args := &chatCmd{File: path, Write: &inPlace, Count: 1}
+		var err error
+		output := captureStdout(t, func() {
+			err = args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{Responses: []string{"hi there"}}))
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "hi there", output)
+		assert.Equal(t, "--- user\nhello\n\n--- assistant\nhi there\n", readChatFile(t, path))
+	})
+
+	t.Run("regenerate alternatives and pick", func(t *testing.T) {
+		path := writeChatFile(t, "--- user\nhello\n\n--- assistant\nold reply\n")
+		config := newFakeConfig(profile.FakeConfig{Responses: []string{"first", "second"}})
+
+		args := &chatCmd{File: path, Write: &inPlace, Count: 2, Regenerate: true}
+		captureStdout(t, func() {
+			require.NoError(t, args.Execute(context.Background(), config))
+		})
+		assert.Equal(t, "--- user\nhello\n\n--- assistant [1/2]\nfirst\n--- assistant [2/2]\nsecond\n", readChatFile(t, path))
+
+		pick := &chatCmd{File: path, Write: &inPlace, Count: 1, Pick: 2}
+		require.NoError(t, pick.Execute(context.Background(), config))
+		assert.Equal(t, "--- user\nhello\n\n--- assistant\nsecond\n", readChatFile(t, path))
+	})
+
+	t.Run("failed reply leaves the file untouched", func(t *testing.T) {
+		original := "--- user\nhello\n"
+		path := writeChatFile(t, original)
+		args := &chatCmd{File: path, Write: &inPlace, Count: 1}
+		var err error
+		captureStdout(t, func() {
+			err = args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{Failure: chat.FakeTruncate}))
+		})
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, original, readChatFile(t, path))
+	})
+
+	t.Run("truncated stream to file is partial", func(t *testing.T) {
+		path := writeChatFile(t, "--- user\none two three four\n")
+		args := &chatCmd{File: path, Write: &inPlace, Count: 1, StreamToFile: true}
+		var err error
+		captureStdout(t, func() {
+			err = args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{Failure: chat.FakeTruncate}))
+		})
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, "--- user\none two three four\n\n--- assistant (partial)\none two\n", readChatFile(t, path))
+	})
+
+	t.Run("template leaves replies alone", func(t *testing.T) {
+		path := writeChatFile(t, "--- user\nhow do I write {{.lang}}?\n\n--- assistant\nuse {{ in go templates\n\n--- user\nthanks\n")
+		args := &chatCmd{File: path, Count: 1, templateArgs: templateArgs{Vars: []string{"lang=templates"}}}
+		request, err := args.prepare(readChatFile(t, path))
+		require.NoError(t, err)
+		require.Len(t, request.Messages, 3)
+		assert.Equal(t, "how do I write templates?\n\n", request.Messages[0].Content)
+		assert.Equal(t, "use {{ in go templates\n\n", request.Messages[1].Content)
+	})
+
+	t.Run("stream to file backs up the original", func(t *testing.T) {
+		original := "--- user\nhello\n"
+		path := writeChatFile(t, original)
+		args := &chatCmd{File: path, Write: &inPlace, Count: 1, StreamToFile: true, Backup: true}
+		captureStdout(t, func() {
+			require.NoError(t, args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{Responses: []string{"hi"}})))
+		})
+		assert.Equal(t, "--- user\nhello\n\n--- assistant\nhi\n", readChatFile(t, path))
+		assert.Equal(t, original, readChatFile(t, path+".bak"))
+	})
+
+	t.Run("empty partial reply", func(t *testing.T) {
+		// the process died before the first token arrived
+		interrupted := "--- user\nhello\n\n--- assistant (partial)\n"
+		config := newFakeConfig(profile.FakeConfig{Responses: []string{"hi"}})
+
+		path := writeChatFile(t, interrupted)
+		args := &chatCmd{File: path, Write: &inPlace, Count: 1}
+		var partial *parse.PartialReplyError
+		assert.ErrorAs(t, args.Execute(context.Background(), config), &partial)
+		assert.Equal(t, interrupted, readChatFile(t, path))
+
+		for _, args := range []*chatCmd{
+			{File: path, Write: &inPlace, Count: 1, Resume: true},
+			{File: path, Write: &inPlace, Count: 1, Regenerate: true},
+		} {
+			require.NoError(t, os.WriteFile(path, []byte(interrupted), 0644))
+			captureStdout(t, func() {
+				require.NoError(t, args.Execute(context.Background(), config))
+			})
+			assert.Equal(t, "--- user\nhello\n\n--- assistant\nhi\n", readChatFile(t, path))
+		}
+	})
+}
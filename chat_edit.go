@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/parse"
+	"github.com/yiblet/hlp/session"
+)
+
+// errEmptyTurn is returned by answerEdit when the user turn was left empty.
+var errEmptyTurn = errors.New("empty user turn")
+
+// edit runs an interactive chat through the user's editor. The chat file is
+// opened with a fresh user turn appended, and once the editor exits the turn
+// is answered and the editor is opened again. The loop ends when the user
+// turn is left empty.
+func (args *chatCmd) edit(ctx context.Context, client chat.Streamer, model string) error {
+	if args.File == "-" {
+		return fmt.Errorf("cannot edit stdin")
+	}
+	if args.Regenerate || args.Watch || args.Write != nil {
+		return fmt.Errorf("--edit always answers in place and cannot be combined with --regenerate, --watch or an output file")
+	}
+	if args.Count > 1 || args.StreamToFile {
+		return fmt.Errorf("--edit answers one turn at a time and cannot be combined with -n or --stream-to-file")
+	}
+
+	for {
+		buf, err := os.ReadFile(args.File)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		input := string(buf)
+		if !args.endsWithUserTurn(input) {
+			input = appendBoundary(input, parse.FormatBoundary("user", 1, 1))
+			if err := args.saveString(args.File, input); err != nil {
+				return err
+			}
+		}
+
+		if err := openEditor(ctx, args.File); err != nil {
+			return fmt.Errorf("editor failed: %w", err)
+		}
+
+		err = args.answerEdit(ctx, client, model)
+		switch {
+		case errors.Is(err, errEmptyTurn):
+			return nil
+		case err != nil:
+			return err
+		}
+	}
+}
+
+// answerEdit answers the user turn of the chat file once the editor exited.
+// If the turn was left empty it is removed and errEmptyTurn is returned. A
+// reply interrupted with Ctrl-C is kept with a truncation marker, so the
+// conversation carries on from it.
+func (args *chatCmd) answerEdit(ctx context.Context, client chat.Streamer, model string) error {
+	unlock, err := lockFile(args.File)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	buf, err := os.ReadFile(args.File)
+	if err != nil {
+		return err
+	}
+	input := string(buf)
+
+	if !args.endsWithUserTurn(input) {
+		// drop the empty user turn we added before stopping
+		trimmed := strings.TrimRight(input, " \t\r\n")
+		if rest, ok := strings.CutSuffix(trimmed, parse.FormatBoundary("user", 1, 1)); ok {
+			input = strings.TrimRight(rest, "\r\n")
+			if input != "" {
+				input += "\n"
+			}
+			if err := args.saveString(args.File, input); err != nil {
+				return err
+			}
+		}
+		return errEmptyTurn
+	}
+
+	input, contents, err := args.respond(ctx, client, model, input, os.Stdout)
+	interrupted := errors.Is(err, errInterrupted)
+	if err != nil && !interrupted {
+		return err
+	}
+	last := &contents[len(contents)-1]
+	if !strings.HasSuffix(*last, "\n") {
+		fmt.Println()
+	}
+	if interrupted {
+		fmt.Printf("%s%s%s\n", colorYellow, strings.TrimSpace(truncationMarker), colorReset)
+		*last = strings.TrimRight(*last, "\n") + "\n" + strings.TrimSuffix(truncationMarker, "\n")
+	}
+
+	var output strings.Builder
+	if err := session.WriteChat(&output, input, contents); err != nil {
+		return err
+	}
+	return args.saveString(args.File, output.String())
+}
+
+// endsWithUserTurn reports whether the chat file input ends with a non-empty
+// user turn.
+func (args *chatCmd) endsWithUserTurn(input string) bool {
+	blocks, err := parse.ParseBlocks(strings.NewReader(input))
+	if err != nil || len(blocks) == 0 {
+		return false
+	}
+	last := blocks[len(blocks)-1]
+	return last.Role == "user" && strings.TrimSpace(last.Content) != ""
+}
+
+// appendBoundary appends a boundary line to the chat file input, separated
+// from the previous turn by an empty line.
+func appendBoundary(input, boundary string) string {
+	input = strings.TrimRight(input, "\r\n")
+	if input == "" {
+		return boundary + "\n"
+	}
+	return input + "\n\n" + boundary + "\n"
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/profile"
+)
+
+// typeOnce is an editor script that writes its input on the first call and
+// leaves the file untouched on the next ones.
+const typeOnce = `count="$0.count"
+if [ ! -e "$count" ]; then
+	touch "$count"
+	printf 'hello\n' >> "$1"
+fi
+`
+
+func TestChatEdit(t *testing.T) {
+	t.Run("answers until a turn is left empty", func(t *testing.T) {
+		fakeEditor(t, typeOnce, "")
+		path := writeChatFile(t, "--- system\nbe brief\n")
+
+		args := &chatCmd{File: path, Count: 1, Edit: true}
+		var err error
+		output := captureStdout(t, func() {
+			err = args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{Responses: []string{"hi there"}}))
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "hi there\n", output)
+		// the empty user turn opened for the second message is removed
+		assert.Equal(t, "--- system\nbe brief\n\n--- user\nhello\n\n--- assistant\nhi there\n", readChatFile(t, path))
+	})
+
+	t.Run("conflicting flags", func(t *testing.T) {
+		path := writeChatFile(t, "--- user\nhello\n")
+		for _, args := range []*chatCmd{
+			{File: path, Count: 2, Edit: true},
+			{File: path, Count: 1, Edit: true, StreamToFile: true},
+		} {
+			err := args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{}))
+			assert.EqualError(t, err, "--edit answers one turn at a time and cannot be combined with -n or --stream-to-file")
+		}
+		assert.Equal(t, "--- user\nhello\n", readChatFile(t, path))
+	})
+
+	t.Run("empty first turn leaves the file as is", func(t *testing.T) {
+		fakeEditor(t, "", "")
+		original := "--- user\nhello\n\n--- assistant\nhi\n"
+		path := writeChatFile(t, original)
+
+		args := &chatCmd{File: path, Count: 1, Edit: true}
+		require.NoError(t, args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{})))
+		assert.Equal(t, original, readChatFile(t, path))
+	})
+
+	t.Run("interrupted reply is kept", func(t *testing.T) {
+		var exits atomic.Int32
+		handler, registered := newTestInterruptHandler(&exits)
+		defer func(previous *interruptHandler) { interrupts = previous }(interrupts)
+		interrupts = handler
+
+		fakeEditor(t, typeOnce, "")
+		path := writeChatFile(t, "")
+		streamer := chat.StreamerFunc(func(ctx context.Context, request chat.Input, onData func(string) error) error {
+			if err := onData("one two"); err != nil {
+				return err
+			}
+			(<-registered) <- os.Interrupt
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		args := &chatCmd{File: path, Count: 1, Edit: true}
+		var err error
+		output := captureStdout(t, func() {
+			err = args.edit(context.Background(), streamer, "gpt-4o-mini")
+		})
+		require.NoError(t, err)
+		assert.Contains(t, output, "one two\n")
+		assert.Contains(t, output, "[reply interrupted by the user]")
+		assert.Equal(t, "--- user\nhello\n\n--- assistant\none two\n\n[reply interrupted by the user]\n", readChatFile(t, path))
+		assert.Equal(t, int32(0), exits.Load())
+	})
+}
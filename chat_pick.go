@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yiblet/hlp/parse"
+)
+
+// pick keeps alternative args.Pick of the replies at the end of the chat file
+// so that the conversation can continue from it, and drops the others. The
+// result is written to the output file, or to stdout if there is none.
+func (args *chatCmd) pick() error {
+	if args.Regenerate || args.Resume || args.Watch || args.Edit || args.StreamToFile || args.Count > 1 {
+		return fmt.Errorf("--pick only picks a reply and cannot be combined with --regenerate, --resume, --watch, --edit, --stream-to-file or -n")
+	}
+
+	outfile, err := args.outputFile()
+	if err != nil {
+		return err
+	}
+	if outfile != "" {
+		unlock, err := lockFile(outfile)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	var file io.ReadCloser = os.Stdin
+	if args.File != "-" {
+		file, err = os.Open(args.File)
+		if err != nil {
+			return err
+		}
+	}
+	buf, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	output, err := parse.Pick(string(buf), args.Pick)
+	if err != nil {
+		return err
+	}
+	if outfile == "" {
+		_, err := fmt.Print(output)
+		return err
+	}
+	return args.saveString(outfile, output)
+}
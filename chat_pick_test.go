@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alexflint/go-arg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/profile"
+)
+
+func TestChatPick(t *testing.T) {
+	const alternatives = "--- user\nhello\n\n--- assistant [1/2]\nfirst\n--- assistant [2/2]\nsecond\n"
+
+	t.Run("parse", func(t *testing.T) {
+		// a chat file named pick is still a chat file
+		var args mainCmd
+		parser, err := arg.NewParser(arg.Config{}, &args)
+		require.NoError(t, err)
+		require.NoError(t, parser.Parse([]string{"-c", "work", "chat", "pick", "-", "--pick", "2"}))
+		require.NotNil(t, args.Chat)
+		assert.Equal(t, "work", args.ConfigName)
+		assert.Equal(t, "pick", args.Chat.File)
+		assert.Equal(t, 2, args.Chat.Pick)
+	})
+
+	t.Run("stdout", func(t *testing.T) {
+		path := writeChatFile(t, alternatives)
+		args := &chatCmd{File: path, Count: 1, Pick: 1}
+		var err error
+		output := captureStdout(t, func() {
+			err = args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{}))
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "--- user\nhello\n\n--- assistant\nfirst\n", output)
+		assert.Equal(t, alternatives, readChatFile(t, path))
+	})
+
+	t.Run("in place", func(t *testing.T) {
+		path := writeChatFile(t, alternatives)
+		inPlace := "-"
+		args := &chatCmd{File: path, Write: &inPlace, Count: 1, Pick: 2, Backup: true}
+		require.NoError(t, args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{})))
+		assert.Equal(t, "--- user\nhello\n\n--- assistant\nsecond\n", readChatFile(t, path))
+		assert.Equal(t, alternatives, readChatFile(t, path+".bak"))
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		path := writeChatFile(t, alternatives)
+		args := &chatCmd{File: path, Count: 1, Pick: 3}
+		err := args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{}))
+		assert.EqualError(t, err, "alternative 3 does not exist, there are 2 alternatives")
+	})
+
+	t.Run("conflicting flags", func(t *testing.T) {
+		path := writeChatFile(t, alternatives)
+		for _, args := range []*chatCmd{
+			{File: path, Count: 1, Pick: 1, Regenerate: true},
+			{File: path, Count: 2, Pick: 1},
+			{File: path, Count: 1, Pick: 1, Watch: true},
+		} {
+			err := args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{}))
+			assert.EqualError(t, err, "--pick only picks a reply and cannot be combined with --regenerate, --resume, --watch, --edit, --stream-to-file or -n")
+		}
+		assert.Equal(t, alternatives, readChatFile(t, path))
+	})
+}
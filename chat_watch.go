@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/parse"
+	"github.com/yiblet/hlp/session"
+)
+
+// watchDebounce is how long the watcher waits after the last change to the
+// chat file before reading it. Editors often save a file in several steps.
+const watchDebounce = 200 * time.Millisecond
+
+// watch answers the chat file every time it is saved with a trailing user
+// turn. The parent directory is watched instead of the file itself since many
+// editors save by replacing the file.
+func (args *chatCmd) watch(ctx context.Context, client chat.Streamer, model string) error {
+	if args.File == "-" {
+		return fmt.Errorf("cannot watch stdin")
+	}
+	if args.Regenerate || args.Edit || args.Write != nil {
+		return fmt.Errorf("--watch always answers in place and cannot be combined with --regenerate, --edit or an output file")
+	}
+
+	path, err := filepath.Abs(args.File)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("cannot watch %s: %w", args.File, err)
+	}
+
+	outputWriter, closeWriter := args.outputWriter()
+	defer closeWriter()
+
+	fmt.Fprintf(os.Stderr, "watching %s, save the file with a new user turn to send it\n", args.File)
+
+	var lastWritten string
+	timer := time.NewTimer(0) // answer the file once on startup
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != path || !event.Has(fsnotify.Write|fsnotify.Create) {
+				continue
+			}
+			timer.Reset(watchDebounce)
+		case <-timer.C:
+			written, err := args.answer(ctx, client, model, path, lastWritten, outputWriter)
+			if err != nil {
+				log.Printf("error: %v", err)
+				continue
+			}
+			if written != "" {
+				lastWritten = written
+			}
+		}
+	}
+}
+
+// answer replies to the chat file at path if it ends with an unanswered user
+// turn. It returns the content written to the file, or an empty string if
+// nothing was written. Content equal to lastWritten is our own write and is
+// ignored.
+func (args *chatCmd) answer(
+	ctx context.Context,
+	client chat.Streamer,
+	model string,
+	path string,
+	lastWritten string,
+	outputWriter io.Writer,
+) (string, error) {
+	unlock, err := lockFile(path)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	input := string(buf)
+	if input == lastWritten {
+		return "", nil
+	}
+
+	blocks, err := parse.ParseBlocks(strings.NewReader(input))
+	if err != nil {
+		return "", err
+	}
+	if len(blocks) == 0 || blocks[len(blocks)-1].Role != "user" {
+		return "", nil
+	}
+
+	input, contents, err := args.respond(ctx, client, model, input, outputWriter)
+	if err != nil {
+		return "", err
+	}
+	if last := contents[len(contents)-1]; !strings.HasSuffix(last, "\n") {
+		fmt.Fprintln(outputWriter)
+	}
+
+	// the file may have been saved again while the reply was generated, in
+	// which case the reply is dropped and the newer save is answered instead
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if string(current) != string(buf) {
+		return "", nil
+	}
+
+	var output strings.Builder
+	if err := session.WriteChat(&output, input, contents); err != nil {
+		return "", err
+	}
+	if err := args.saveString(path, output.String()); err != nil {
+		return "", err
+	}
+	return output.String(), nil
+}
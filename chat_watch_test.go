@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/chat"
+)
+
+// recorder is a streamer that answers "re: " and the last message, and
+// records every request.
+type recorder struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (r *recorder) ChatStream(ctx context.Context, request chat.Input, onData func(string) error) error {
+	last := strings.TrimSpace(request.Messages[len(request.Messages)-1].Content)
+	r.mu.Lock()
+	r.requests = append(r.requests, last)
+	r.mu.Unlock()
+	return onData("re: " + last)
+}
+
+func (r *recorder) seen() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.requests...)
+}
+
+func TestChatWatch(t *testing.T) {
+	path := writeChatFile(t, "--- user\nfirst\n")
+	client := &recorder{}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	captureStdout(t, func() {
+		done := make(chan error)
+		go func() {
+			args := &chatCmd{File: path, Count: 1, Watch: true}
+			done <- args.watch(ctx, client, "gpt-4o-mini")
+		}()
+
+		// the unanswered turn is answered on startup
+		answered := "--- user\nfirst\n\n--- assistant\nre: first\n"
+		require.Eventually(t, func() bool { return readChatFile(t, path) == answered }, 5*time.Second, 10*time.Millisecond)
+
+		// our own write is not answered again
+		time.Sleep(3 * watchDebounce)
+		assert.Equal(t, []string{"first"}, client.seen())
+
+		// two saves within the debounce window are answered once
+		require.NoError(t, os.WriteFile(path, []byte(answered+"\n--- user\nsec\n"), 0644))
+		require.NoError(t, os.WriteFile(path, []byte(answered+"\n--- user\nsecond\n"), 0644))
+		require.Eventually(t, func() bool {
+			return strings.HasSuffix(readChatFile(t, path), "--- user\nsecond\n\n--- assistant\nre: second\n")
+		}, 5*time.Second, 10*time.Millisecond)
+		time.Sleep(3 * watchDebounce)
+		assert.Equal(t, []string{"first", "second"}, client.seen())
+
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yiblet/hlp/cache"
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/profile"
+	"github.com/yiblet/hlp/session"
+)
+
+type compareCmd struct {
+	Question    []string `arg:"positional"`
+	Models      []string `arg:"--model,-m,separate,required" help:"a model to compare, pass it once for every model"`
+	MaxTokens   int      `arg:"--tokens,-t" default:"0" help:"the maximum amount of tokens allowed in each answer"`
+	Temperature *float32 `arg:"--temp"`
+	Prompt      string   `arg:"--prompt,-p" help:"use a named prompt from the library as the system prompt"`
+	Attach      []string `arg:"--attach,-a,separate" help:"attach additional files at the end of the message. pass '-' to pass in stdin"`
+	templateArgs
+}
+
+// compareResult is the answer of one model.
+type compareResult struct {
+	Model      string
+	Reply      string
+	Usage      chat.Usage
+	FirstToken time.Duration
+	Latency    time.Duration
+	Err        error
+}
+
+func (args *compareCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	ask := &askCmd{
+		Question:     args.Question,
+		Prompt:       args.Prompt,
+		Attach:       args.Attach,
+		templateArgs: args.templateArgs,
+	}
+	if ask.Prompt == "" {
+		ask.Prompt = config.DefaultPrompt()
+	}
+	content, err := ask.buildContent(ctx, config.Project)
+	if err != nil {
+		return fmt.Errorf("cannot build message: %w", err)
+	}
+	messages, err := ask.messages(config.Prompts(), content)
+	if err != nil {
+		return err
+	}
+
+	client, err := config.Client()
+	if err != nil {
+		return err
+	}
+
+	out := newSections(os.Stdout, args.Models)
+	results := make([]compareResult, len(args.Models))
+	err = interrupts.run(ctx, func(ctx context.Context) error {
+		var wg sync.WaitGroup
+		for i, model := range args.Models {
+			wg.Add(1)
+			go func(i int, model string) {
+				defer wg.Done()
+				results[i] = args.run(ctx, client, model, messages, func(message string) {
+					out.write(i, message)
+				})
+				out.finish(i, results[i].Err)
+			}(i, model)
+		}
+		wg.Wait()
+		return nil
+	})
+	interrupted := errors.Is(err, errInterrupted)
+	if err != nil && !interrupted {
+		return err
+	}
+
+	fmt.Println()
+	if err := writeSummary(os.Stdout, config, results); err != nil {
+		return err
+	}
+	if interrupted {
+		return errInterrupted
+	}
+
+	for _, result := range results {
+		if result.Err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("every model failed: %w", results[0].Err)
+}
+
+// run streams the answer of model to onData and measures it.
+func (args *compareCmd) run(ctx context.Context, client chat.Streamer, model string, messages []chat.Message, onData func(message string)) compareResult {
+	result := compareResult{Model: model}
+	reported := false
+	// a cached answer would measure the cache instead of the model
+	ctx = cache.SkipLookup(ctx)
+	ctx = chat.WithUsage(ctx, func(usage chat.Usage) {
+		result.Usage = usage
+		reported = true
+	})
+
+	conversation := &session.Conversation{
+		Streamer:    client,
+		Model:       model,
+		MaxTokens:   args.MaxTokens,
+		Temperature: args.Temperature,
+		Messages:    messages,
+	}
+	start := time.Now()
+	result.Reply, result.Err = conversation.Generate(ctx, func(message string) error {
+		if result.FirstToken == 0 {
+			result.FirstToken = time.Since(start)
+		}
+		onData(message)
+		return nil
+	})
+	result.Latency = time.Since(start)
+
+	if !reported {
+		result.Usage = chat.EstimateUsage(messages, result.Reply)
+	}
+	return result
+}
+
+// sections writes the answers of several models one after the other, each
+// under its label, while they are generated concurrently. The answer being
+// written is streamed and the others are buffered until it is their turn.
+type sections struct {
+	mu      sync.Mutex
+	w       io.Writer
+	labels  []string
+	buffers []strings.Builder
+	done    []bool
+	current int
+	// newline reports whether the output ends with a newline.
+	newline bool
+}
+
+func newSections(w io.Writer, labels []string) *sections {
+	s := &sections{
+		w:       w,
+		labels:  labels,
+		buffers: make([]strings.Builder, len(labels)),
+		done:    make([]bool, len(labels)),
+		newline: true,
+	}
+	s.header(0)
+	return s
+}
+
+func (s *sections) header(i int) {
+	if !s.newline {
+		fmt.Fprintln(s.w)
+	}
+	if i > 0 {
+		fmt.Fprintln(s.w)
+	}
+	fmt.Fprintf(s.w, "%s==> %s <==%s\n", colorCyan, s.labels[i], colorReset)
+	s.newline = true
+}
+
+func (s *sections) print(text string) {
+	if text == "" {
+		return
+	}
+	fmt.Fprint(s.w, text)
+	s.newline = strings.HasSuffix(text, "\n")
+}
+
+func (s *sections) write(i int, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i == s.current {
+		s.print(text)
+	} else {
+		s.buffers[i].WriteString(text)
+	}
+}
+
+// finish ends the answer of section i, and moves on to the next sections
+// that are waiting.
+func (s *sections) finish(i int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		message := fmt.Sprintf("%serror: %v%s\n", colorRed, err, colorReset)
+		if i == s.current {
+			if !s.newline {
+				s.print("\n")
+			}
+			s.print(message)
+		} else {
+			if text := s.buffers[i].String(); text != "" && !strings.HasSuffix(text, "\n") {
+				s.buffers[i].WriteString("\n")
+			}
+			s.buffers[i].WriteString(message)
+		}
+	}
+	s.done[i] = true
+
+	for s.current < len(s.labels) && s.done[s.current] {
+		s.current++
+		if s.current < len(s.labels) {
+			s.header(s.current)
+			s.print(s.buffers[s.current].String())
+			s.buffers[s.current].Reset()
+		}
+	}
+	if s.current == len(s.labels) && !s.newline {
+		s.print("\n")
+	}
+}
+
+// writeSummary writes a table of the latency, tokens and cost of every
+// answer. Token counts that were estimated are marked with "~".
+func writeSummary(w io.Writer, config *profile.Profile, results []compareResult) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "MODEL\tFIRST TOKEN\tLATENCY\tINPUT\tOUTPUT\tCOST\tSTATUS\n")
+	for _, result := range results {
+		approx := ""
+		if result.Usage.Estimated {
+			approx = "~"
+		}
+		cost := "-"
+		if price, ok := config.Price(result.Model); ok {
+			cost = fmt.Sprintf("%s$%.4f", approx, price.Cost(result.Usage))
+		}
+		firstToken := "-"
+		if result.FirstToken > 0 {
+			firstToken = result.FirstToken.Round(time.Millisecond).String()
+		}
+		status := "ok"
+		if result.Err != nil {
+			status = "error"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s%d\t%s%d\t%s\t%s\n",
+			result.Model, firstToken, result.Latency.Round(time.Millisecond),
+			approx, result.Usage.InputTokens, approx, result.Usage.OutputTokens, cost, status)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/profile"
+)
+
+func TestCompareCmd(t *testing.T) {
+	t.Run("answers", func(t *testing.T) {
+		config := newFakeConfig(profile.FakeConfig{})
+		config.Providers = map[string]*profile.ProviderConfig{"alt": {Type: profile.ProviderFake}}
+
+		args := &compareCmd{Question: []string{"hello there"}, Models: []string{"gpt-4o", "alt/llama3"}}
+		var err error
+		output := captureStdout(t, func() {
+			err = args.Execute(context.Background(), config)
+		})
+		require.NoError(t, err)
+
+		first := strings.Index(output, "==> gpt-4o <==")
+		second := strings.Index(output, "==> alt/llama3 <==")
+		require.True(t, first >= 0 && second > first, output)
+		assert.Equal(t, 2, strings.Count(output, "hello there\n"))
+		assert.Regexp(t, `gpt-4o\s+\S+\s+\S+\s+3\s+3\s+\$0\.0000\s+ok`, output)
+		assert.Regexp(t, `alt/llama3\s+\S+\s+\S+\s+3\s+3\s+-\s+ok`, output)
+	})
+
+	t.Run("skips the cache", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("the config directory is only moved on linux")
+		}
+		t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+		for _, reply := range []string{"first", "second"} {
+			config := newFakeConfig(profile.FakeConfig{Responses: []string{reply}})
+			config.Cache = &profile.CacheConfig{Enabled: true}
+			args := &compareCmd{Question: []string{"hello"}, Models: []string{"a", "b"}}
+			var err error
+			output := captureStdout(t, func() {
+				err = args.Execute(context.Background(), config)
+			})
+			require.NoError(t, err)
+			assert.Equal(t, 2, strings.Count(output, reply+"\n"), output)
+		}
+	})
+
+	t.Run("every model fails", func(t *testing.T) {
+		args := &compareCmd{Question: []string{"hello"}, Models: []string{"a", "b"}}
+		var err error
+		output := captureStdout(t, func() {
+			err = args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{Failure: chat.FakeRateLimit}))
+		})
+		var statusErr *chat.FakeStatusError
+		assert.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, 2, strings.Count(output, "error: fake provider: 429"))
+	})
+}
+
+func TestSections(t *testing.T) {
+	var buf bytes.Buffer
+	s := newSections(&buf, []string{"a", "b", "c"})
+	s.write(1, "second")
+	s.write(0, "first\n")
+	s.write(2, "third")
+	s.finish(2, nil)
+	s.finish(0, nil)
+	s.write(1, " more")
+	s.finish(1, nil)
+
+	expected := colorCyan + "==> a <==" + colorReset + "\nfirst\n\n" +
+		colorCyan + "==> b <==" + colorReset + "\nsecond more\n\n" +
+		colorCyan + "==> c <==" + colorReset + "\nthird\n"
+	assert.Equal(t, expected, buf.String())
+}
@@ -1,33 +1,47 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
+	"text/tabwriter"
+
+	"github.com/yiblet/hlp/profile"
+	"github.com/yiblet/hlp/secret"
 )
 
 type configCmd struct {
-	Set  *configSetCmd  `arg:"subcommand"`
-	Get  *configGetCmd  `arg:"subcommand"`
-	Path *configPathCmd `arg:"subcommand"`
+	Set     *configSetCmd     `arg:"subcommand:set" help:"set a config key"`
+	Get     *configGetCmd     `arg:"subcommand:get" help:"print the value of a config key"`
+	Unset   *configUnsetCmd   `arg:"subcommand:unset" help:"remove a config key so its default applies"`
+	List    *configListCmd    `arg:"subcommand:list" help:"list every config key and its value"`
+	Explain *configExplainCmd `arg:"subcommand:explain" help:"print the effective value of every config key and where it comes from"`
+	Path    *configPathCmd    `arg:"subcommand:path" help:"print the config directory"`
 }
 
-func (c *configCmd) Execute(ctx context.Context, config *config) error {
+func (c *configCmd) Execute(ctx context.Context, config *profile.Profile) error {
 	switch {
 	case c.Set != nil:
 		return c.Set.Execute(ctx, config)
 	case c.Get != nil:
 		return c.Get.Execute(ctx, config)
+	case c.Unset != nil:
+		return c.Unset.Execute(ctx, config)
+	case c.List != nil:
+		return c.List.Execute(ctx, config)
+	case c.Explain != nil:
+		return c.Explain.Execute(ctx, config)
 	case c.Path != nil:
 		return c.Path.Execute(ctx, config)
 	default:
 		buf := bytes.NewBuffer([]byte{})
 		enc := json.NewEncoder(buf)
 		enc.SetIndent("", "  ")
-		err := enc.Encode(config)
+		err := enc.Encode(config.Masked())
 		if err != nil {
 			return err
 		}
@@ -39,166 +53,157 @@ func (c *configCmd) Execute(ctx context.Context, config *config) error {
 
 type configPathCmd struct{}
 
-func (c *configPathCmd) Execute(ctx context.Context, config *config) error {
-	fmt.Printf("%s\n", getConfigPath())
+func (c *configPathCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	fmt.Printf("%s\n", profile.Dir())
 	return nil
 }
 
 type configGetCmd struct {
-	Model *struct {
-	} `arg:"subcommand:model"`
-	OpenAIAPIKey *struct {
-	} `arg:"subcommand:openai_api_key"`
-	OpenAIAPIEndpoint *struct {
-	} `arg:"subcommand:openai_api_endpoint"`
+	Key string `arg:"positional,required" help:"the key, such as model or providers.local.endpoint"`
 }
 
-func (c *configGetCmd) Execute(ctx context.Context, config *config) error {
-	switch {
-	case c.Model != nil:
-		return executeGet(config, modelKeyValue{})
-	case c.OpenAIAPIKey != nil:
-		return executeGet(config, openaiKeyValue{})
-	case c.OpenAIAPIEndpoint != nil:
-		return executeGet(config, openaiEndpointValue{})
-	default:
-		return writeHelp(c, os.Stderr)
+func (c *configGetCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	setting, err := config.Get(c.Key)
+	if err != nil {
+		return err
 	}
-}
-
-type configSetCmd struct {
-	Model *struct {
-		Model string `arg:"positional"`
-	} `arg:"subcommand:model"`
-	OpenAIAPIKey *struct {
-		OpenAIAPIKey string `arg:"positional"`
-	} `arg:"subcommand:openai_api_key"`
-	OpenAIAPIEndpoint *struct {
-		OpenAIAPIEndpoint string `arg:"positional"`
-	} `arg:"subcommand:openai_api_endpoint"`
-}
-
-func (c *configSetCmd) Execute(ctx context.Context, config *config) error {
-	switch {
-	case c.Model != nil:
-		return executeSet(config, modelKeyValue{}, c.Model.Model)
-	case c.OpenAIAPIKey != nil:
-		return executeSet(config, openaiKeyValue{}, c.OpenAIAPIKey.OpenAIAPIKey)
-	case c.OpenAIAPIEndpoint != nil:
-		return executeSet(config, openaiEndpointValue{}, c.OpenAIAPIEndpoint.OpenAIAPIEndpoint)
-	default:
-		return writeHelp(c, os.Stderr)
+	if setting.Value == "" {
+		return fmt.Errorf("%s is not set", setting.Name)
 	}
-}
-
-type configValue interface {
-	set(config *config, value string) error
-	get(config *config) string
-	name() string
-}
-
-type openaiEndpointValue struct{}
 
-func (openaiEndpointValue) set(config *config, value string) error {
-	config.OpenAIAPIEndpoint = value
+	fmt.Printf("%s\n", setting.Value)
 	return nil
 }
 
-func (openaiEndpointValue) get(config *config) string {
-	return config.OpenAIAPIEndpoint
+type configSetCmd struct {
+	Key   string  `arg:"positional,required" help:"the key, such as model or providers.local.endpoint"`
+	Value *string `arg:"positional" help:"the value, read from the key's environment variable or prompted for if missing"`
 }
 
-func (openaiEndpointValue) fromEnv() string {
-	val, _ := os.LookupEnv("OPENAI_API_ENDPOINT")
-	return val
-}
+func (c *configSetCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	key, err := profile.LookupKey(c.Key)
+	if err != nil {
+		return err
+	}
 
-func (openaiEndpointValue) name() string {
-	return "openai api endpoint"
-}
+	var value string
+	if c.Value != nil {
+		value = *c.Value
+	} else if value, err = readValue(key, c.Key); err != nil {
+		return err
+	}
 
-type openaiKeyValue struct{}
+	if err := config.Set(c.Key, value); err != nil {
+		return err
+	}
+	if err := config.Write(); err != nil {
+		return err
+	}
 
-func (openaiKeyValue) set(config *config, value string) error {
-	config.OpenAIAPIKey = value
+	fmt.Printf("%s stored in config\n", c.Key)
 	return nil
 }
 
-func (openaiKeyValue) get(config *config) string {
-	return config.OpenAIAPIKey
-}
+// readValue reads the value of a key that was not passed on the command
+// line from its environment variable, or from stdin.
+func readValue(key *profile.Key, name string) (string, error) {
+	if key.Env != "" {
+		if value := strings.TrimSpace(os.Getenv(key.Env)); value != "" {
+			fmt.Printf("%s is set from %s\n", name, key.Env)
+			return value, nil
+		}
+		fmt.Printf("The %s was not passed in via %s or command line argument. Enter it in the following line:\n", name, key.Env)
+	} else {
+		fmt.Printf("The %s was not passed in via command line argument. Enter it in the following line:\n", name)
+	}
 
-func (openaiKeyValue) fromEnv() string {
-	val, _ := os.LookupEnv("OPENAI_API_KEY")
-	return val
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+
+	value := strings.TrimSpace(line)
+	if value == "" {
+		return "", fmt.Errorf("invalid %s", name)
+	}
+	return value, nil
 }
 
-func (openaiKeyValue) name() string {
-	return "openai api key"
+type configUnsetCmd struct {
+	Key string `arg:"positional,required" help:"the key, such as model or providers.local.endpoint"`
 }
 
-type modelKeyValue struct{}
+func (c *configUnsetCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	if err := config.Unset(c.Key); err != nil {
+		return err
+	}
+	if err := config.Write(); err != nil {
+		return err
+	}
 
-func (modelKeyValue) set(config *config, value string) error {
-	config.DefaultModel = value
+	fmt.Printf("%s removed from config\n", c.Key)
 	return nil
 }
 
-func (modelKeyValue) get(config *config) string {
-	return config.DefaultModel
-}
-
-func (modelKeyValue) name() string {
-	return "openai model"
+type configListCmd struct {
+	All bool `arg:"--all" help:"also list the keys that are not set and have no default"`
 }
 
-func executeSet(config *config, configVal configValue, value string) error {
-	if env, ok := configVal.(interface{ fromEnv() string }); value == "" && ok {
-		value = strings.TrimSpace(env.fromEnv())
-		if value != "" {
-			fmt.Printf("%s is set from env\n", configVal.name())
-		}
+func (c *configListCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	settings, err := config.List()
+	if err != nil {
+		return err
 	}
 
-	if value == "" {
-		_, hasEnv := configVal.(interface{ fromEnv() string })
-		if hasEnv {
-			fmt.Printf("The %s was not passed in via env or command line argument. Enter it in the following line:\n", configVal.name())
-		} else {
-			fmt.Printf("The %s was not passed in via command line argument. Enter it in the following line:\n", configVal.name())
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, setting := range settings {
+		if !setting.IsSet && setting.Value == "" && !c.All {
+			continue
 		}
 
-		var key string
-		_, err := fmt.Scanf("%s\n", &key)
-		if err != nil {
-			return err
+		value := setting.Value
+		if setting.Key.Secret {
+			value = secret.Mask(value)
 		}
-
-		key = strings.TrimSpace(key)
-		if key == "" {
-			return fmt.Errorf("invalid %s", configVal.name())
+		switch {
+		case !setting.IsSet && value == "":
+			value = "(not set)"
+		case !setting.IsSet:
+			value += " (default)"
 		}
-		value = key
+		fmt.Fprintf(w, "%s\t%s\n", setting.Name, value)
 	}
+	return w.Flush()
+}
 
-	if err := configVal.set(config, value); err != nil {
-		return err
-	}
+type configExplainCmd struct {
+	All bool `arg:"--all" help:"also list the keys that are not set and have no default"`
+}
 
-	fmt.Printf("saving %s...\n", configVal.name())
-	if err := config.Write(); err != nil {
+func (c *configExplainCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	settings, err := config.Explain()
+	if err != nil {
 		return err
 	}
 
-	fmt.Printf("%s stored in cofig\n", configVal.name())
-	return nil
-}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, setting := range settings {
+		if setting.Value == "" && !c.All {
+			continue
+		}
 
-func executeGet(config *config, configVal configValue) error {
-	if value := configVal.get(config); value != "" {
-		fmt.Printf("%s\n", value)
-		return nil
+		value := setting.Value
+		if setting.Key.Secret {
+			value = secret.Mask(value)
+		}
+		if value == "" {
+			value = "(not set)"
+		}
+		source := setting.Source
+		if setting.Origin != "" {
+			source += " " + setting.Origin
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", setting.Name, value, source)
 	}
-
-	return fmt.Errorf("value is empty")
+	return w.Flush()
 }
@@ -0,0 +1,185 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/session"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// the kinds of assertions
+const (
+	KindContains   = "contains"
+	KindRegex      = "regex"
+	KindJSONSchema = "json_schema"
+	KindBash       = "bash"
+	KindJudge      = "judge"
+)
+
+// Assertion checks an answer. Exactly one of its fields is set.
+type Assertion struct {
+	// Contains is a substring the answer must contain.
+	Contains string `yaml:"contains"`
+	// Regex is a regular expression the answer must match.
+	Regex string `yaml:"regex"`
+	// JSONSchema is a JSON schema the answer must be valid JSON for.
+	JSONSchema any `yaml:"json_schema"`
+	// Bash requires the answer to parse as a bash script.
+	Bash bool `yaml:"bash"`
+	// Judge is a rubric the judge model grades the answer by.
+	Judge string `yaml:"judge"`
+
+	regex *regexp.Regexp
+}
+
+// Kind returns the kind of the assertion.
+func (a *Assertion) Kind() string {
+	switch {
+	case a.Contains != "":
+		return KindContains
+	case a.Regex != "":
+		return KindRegex
+	case a.JSONSchema != nil:
+		return KindJSONSchema
+	case a.Bash:
+		return KindBash
+	case a.Judge != "":
+		return KindJudge
+	}
+	return ""
+}
+
+func (a *Assertion) validate() error {
+	set := 0
+	for _, ok := range []bool{a.Contains != "", a.Regex != "", a.JSONSchema != nil, a.Bash, a.Judge != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("expected exactly one of %s, %s, %s, %s or %s", KindContains, KindRegex, KindJSONSchema, KindBash, KindJudge)
+	}
+
+	switch {
+	case a.Regex != "":
+		regex, err := regexp.Compile(a.Regex)
+		if err != nil {
+			return err
+		}
+		a.regex = regex
+	case a.JSONSchema != nil:
+		schema, ok := normalize(a.JSONSchema).(map[string]any)
+		if !ok {
+			return fmt.Errorf("json_schema must be an object")
+		}
+		if err := checkSchema(schema, "json_schema"); err != nil {
+			return err
+		}
+		a.JSONSchema = schema
+	}
+	return nil
+}
+
+// AssertionResult is the outcome of an assertion.
+type AssertionResult struct {
+	Kind   string `json:"kind"`
+	Passed bool   `json:"passed"`
+	// Message explains why the assertion failed.
+	Message string `json:"message,omitempty"`
+}
+
+// judge grades answers by a rubric with a model.
+type judge struct {
+	streamer chat.Streamer
+	model    string
+}
+
+// judgeSystem is the system prompt of the judge model.
+const judgeSystem = `You grade the answer of an AI assistant against a rubric.
+Reply with PASS or FAIL on the first line, and a one sentence reason on the second line.`
+
+func (j *judge) grade(ctx context.Context, rubric, answer string) (bool, string, error) {
+	conversation := &session.Conversation{
+		Streamer: j.streamer,
+		Model:    j.model,
+		Messages: session.Question(judgeSystem, fmt.Sprintf("Rubric:\n%s\n\nAnswer:\n%s", rubric, answer)),
+	}
+	verdict, err := conversation.Generate(ctx, nil)
+	if err != nil {
+		return false, "", fmt.Errorf("judge: %w", err)
+	}
+
+	first, reason, _ := strings.Cut(strings.TrimSpace(verdict), "\n")
+	first = strings.ToUpper(strings.Trim(strings.TrimSpace(first), "*.:"))
+	reason = strings.TrimSpace(reason)
+	switch {
+	case strings.HasPrefix(first, "PASS"):
+		return true, reason, nil
+	case strings.HasPrefix(first, "FAIL"):
+		return false, reason, nil
+	default:
+		return false, "", fmt.Errorf("judge: unexpected verdict %q", verdict)
+	}
+}
+
+// check runs the assertion against answer.
+func (a *Assertion) check(ctx context.Context, answer string, judge *judge) AssertionResult {
+	result := AssertionResult{Kind: a.Kind(), Passed: true}
+	fail := func(format string, args ...any) AssertionResult {
+		result.Passed = false
+		result.Message = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	switch result.Kind {
+	case KindContains:
+		if !strings.Contains(answer, a.Contains) {
+			return fail("does not contain %q", a.Contains)
+		}
+	case KindRegex:
+		if !a.regex.MatchString(answer) {
+			return fail("does not match %q", a.Regex)
+		}
+	case KindJSONSchema:
+		var value any
+		if err := json.Unmarshal([]byte(stripFence(answer)), &value); err != nil {
+			return fail("invalid JSON: %v", err)
+		}
+		if err := validateSchema(a.JSONSchema.(map[string]any), value, "$"); err != nil {
+			return fail("%v", err)
+		}
+	case KindBash:
+		parser := syntax.NewParser(syntax.Variant(syntax.LangBash))
+		if _, err := parser.Parse(strings.NewReader(stripFence(answer)), ""); err != nil {
+			return fail("invalid bash: %v", err)
+		}
+	case KindJudge:
+		passed, reason, err := judge.grade(ctx, a.Judge, answer)
+		if err != nil {
+			return fail("%v", err)
+		}
+		result.Passed = passed
+		result.Message = reason
+	}
+	return result
+}
+
+// stripFence returns the content of a markdown code block around the
+// answer, or the answer itself if there is none.
+func stripFence(answer string) string {
+	trimmed := strings.TrimSpace(answer)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") || len(trimmed) < 6 {
+		return answer
+	}
+	body := strings.TrimSuffix(trimmed[3:], "```")
+	if _, rest, ok := strings.Cut(body, "\n"); ok {
+		// drop the language of the block
+		body = rest
+	}
+	return body
+}
@@ -0,0 +1,20 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errNoModels = errors.New("no models to run the cases against")
+
+// InvalidSuiteError is returned for suites that cannot be run.
+type InvalidSuiteError struct {
+	Path string
+	Err  error
+}
+
+func (e *InvalidSuiteError) Error() string {
+	return fmt.Sprintf("invalid suite %s: %v", e.Path, e.Err)
+}
+
+func (e *InvalidSuiteError) Unwrap() error { return e.Err }
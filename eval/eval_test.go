@@ -0,0 +1,221 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/prompt"
+)
+
+// fakeModels answers as the model "good" that passes the suite in testdata,
+// the model "bad" that fails it, and the judge.
+func fakeModels(t *testing.T) chat.Streamer {
+	return chat.StreamerFunc(func(ctx context.Context, request chat.Input, onData func(string) error) error {
+		last := request.Messages[len(request.Messages)-1].Content
+		var reply string
+		switch {
+		case request.Model == "judge" && strings.Contains(last, "Answer:\nls "):
+			reply = "PASS\nit lists files"
+		case request.Model == "judge":
+			reply = "FAIL\nit deletes files"
+		case request.Model == "good" && last == "Review main.go\n":
+			assert.Equal(t, "Reply with JSON.\n", request.Messages[0].Content)
+			reply = "```json\n{\"issues\": [\"unused import\"]}\n```"
+		case request.Model == "good":
+			assert.Equal(t, "list the files in /tmp", last)
+			assert.Equal(t, "output bash", request.Messages[0].Content)
+			reply = "ls -la /tmp"
+		case last == "Review main.go\n":
+			reply = `{"issues": []}`
+		default:
+			reply = "rm -rf /tmp; (("
+		}
+		return onData(reply)
+	})
+}
+
+func runSuite(t *testing.T) *Report {
+	t.Helper()
+	suite, err := Load("testdata/suite.yaml")
+	require.NoError(t, err)
+
+	runner := &Runner{
+		Streamer: fakeModels(t),
+		Prompts:  &prompt.Library{Dir: t.TempDir(), Builtins: map[string]string{"bash": "output bash"}},
+	}
+	report, err := runner.Run(context.Background(), suite, nil)
+	require.NoError(t, err)
+	return report
+}
+
+func TestRun(t *testing.T) {
+	report := runSuite(t)
+	require.Len(t, report.Results, 4)
+
+	byName := map[string]Result{}
+	for _, result := range report.Results {
+		byName[result.Case+"/"+result.Model] = result
+	}
+	for _, name := range []string{"list files/good", "structured/good"} {
+		assert.True(t, byName[name].Passed, name)
+		assert.Empty(t, byName[name].Error, name)
+	}
+
+	bad := byName["list files/bad"]
+	assert.False(t, bad.Passed)
+	var kinds []string
+	for _, assertion := range bad.Assertions {
+		if !assertion.Passed {
+			kinds = append(kinds, assertion.Kind)
+		}
+	}
+	assert.Equal(t, []string{KindBash, KindContains, KindRegex, KindJudge}, kinds)
+
+	structured := byName["structured/bad"]
+	assert.False(t, structured.Passed)
+	assert.Contains(t, structured.Assertions[0].Message, "at least 1 items")
+
+	assert.Equal(t, []Summary{
+		{Model: "good", Passed: 2, Total: 2, PassRate: 1},
+		{Model: "bad", Passed: 0, Total: 2, PassRate: 0},
+	}, report.Summaries())
+	assert.Equal(t, 2, report.Failed())
+}
+
+func TestRunInterrupted(t *testing.T) {
+	suite, err := Load("testdata/suite.yaml")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runner := &Runner{
+		Streamer: chat.StreamerFunc(func(ctx context.Context, request chat.Input, onData func(string) error) error {
+			cancel()
+			return ctx.Err()
+		}),
+		Prompts:     &prompt.Library{Dir: t.TempDir(), Builtins: map[string]string{"bash": "output bash"}},
+		Concurrency: 1,
+	}
+	report, err := runner.Run(ctx, suite, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// the first case was cut short, and the others never ran
+	require.Len(t, report.Results, 1)
+	assert.Equal(t, 3, report.NotRun)
+	assert.Equal(t, 1, report.Failed())
+	total := 0
+	for _, summary := range report.Summaries() {
+		total += summary.Total
+	}
+	assert.Equal(t, 1, total)
+
+	var junit bytes.Buffer
+	require.NoError(t, report.Write(&junit, FormatJUnit))
+	var suites junitSuites
+	require.NoError(t, xml.Unmarshal(junit.Bytes(), &suites))
+	require.Len(t, suites.Suites, 2)
+	assert.Equal(t, 1, suites.Suites[0].Tests+suites.Suites[1].Tests)
+
+	var text bytes.Buffer
+	require.NoError(t, report.Write(&text, FormatText))
+	assert.Contains(t, text.String(), "3 results were not run")
+}
+
+func TestReportFormats(t *testing.T) {
+	report := runSuite(t)
+
+	var text bytes.Buffer
+	require.NoError(t, report.Write(&text, FormatText))
+	assert.Contains(t, text.String(), "PASS  list files  [good]")
+	assert.Contains(t, text.String(), "FAIL  list files  [bad]")
+	assert.Regexp(t, `bad\s+0/2\s+0%`, text.String())
+
+	var decoded struct {
+		Results []Result  `json:"results"`
+		Summary []Summary `json:"summary"`
+	}
+	var js bytes.Buffer
+	require.NoError(t, report.Write(&js, FormatJSON))
+	require.NoError(t, json.Unmarshal(js.Bytes(), &decoded))
+	assert.Len(t, decoded.Results, 4)
+	assert.Equal(t, report.Summaries(), decoded.Summary)
+
+	var junit bytes.Buffer
+	require.NoError(t, report.Write(&junit, FormatJUnit))
+	var suites junitSuites
+	require.NoError(t, xml.Unmarshal(junit.Bytes(), &suites))
+	require.Len(t, suites.Suites, 2)
+	assert.Equal(t, "bad", suites.Suites[1].Name)
+	assert.Equal(t, 2, suites.Suites[1].Failures)
+	assert.Equal(t, 0, suites.Suites[0].Failures)
+
+	assert.Error(t, report.Write(&text, "yaml"))
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown key":     "cases:\n  - input: hi\n    asert: [{contains: x}]\n",
+		"two assertions":  "cases:\n  - input: hi\n    assert: [{contains: x, regex: y}]\n",
+		"bad regex":       "cases:\n  - input: hi\n    assert: [{regex: '('}]\n",
+		"schema keyword":  "cases:\n  - input: hi\n    assert: [{json_schema: {type: object, properties: {a: {anyOf: [{type: string}]}}}}]\n",
+		"schema items":    "cases:\n  - input: hi\n    assert: [{json_schema: {items: [{type: string}]}}]\n",
+		"schema required": "cases:\n  - input: hi\n    assert: [{json_schema: {type: object, required: issues}}]\n",
+		"schema enum":     "cases:\n  - input: hi\n    assert: [{json_schema: {enum: a}}]\n",
+		"schema type":     "cases:\n  - input: hi\n    assert: [{json_schema: {type: [string, text]}}]\n",
+		"schema minimum":  "cases:\n  - input: hi\n    assert: [{json_schema: {minimum: '1'}}]\n",
+		"no messages":     "cases:\n  - name: empty\n    assert: [{contains: x}]\n",
+		"no cases":        "models: [a]\n",
+	}
+	for name, content := range tests {
+		path := filepath.Join(t.TempDir(), "suite.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		_, err := Load(path)
+		var invalid *InvalidSuiteError
+		assert.ErrorAs(t, err, &invalid, name)
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	schema := normalize(map[string]any{
+		"type":                 "object",
+		"required":             []any{"name"},
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"name":  map[string]any{"type": "string", "pattern": "^[a-z]+$"},
+			"count": map[string]any{"type": "integer", "minimum": 0},
+			"kind":  map[string]any{"enum": []any{"a", "b"}},
+		},
+	}).(map[string]any)
+
+	tests := map[string]bool{
+		`{"name": "abc", "count": 2, "kind": "a"}`: true,
+		`{"name": "abc"}`:                          true,
+		`{"count": 2}`:                             false,
+		`{"name": "ABC"}`:                          false,
+		`{"name": "abc", "count": 1.5}`:            false,
+		`{"name": "abc", "count": -1}`:             false,
+		`{"name": "abc", "kind": "c"}`:             false,
+		`{"name": "abc", "other": 1}`:              false,
+		`["abc"]`:                                  false,
+	}
+	for input, valid := range tests {
+		var value any
+		require.NoError(t, json.Unmarshal([]byte(input), &value))
+		err := validateSchema(schema, value, "$")
+		assert.Equal(t, valid, err == nil, "%s: %v", input, err)
+	}
+
+	require.NoError(t, checkSchema(schema, "json_schema"))
+	unsupported := normalize(map[string]any{
+		"properties": map[string]any{"tags": map[string]any{"type": "array", "uniqueItems": true}},
+	}).(map[string]any)
+	assert.EqualError(t, checkSchema(unsupported, "json_schema"), `json_schema.properties.tags: unsupported keyword "uniqueItems"`)
+}
@@ -0,0 +1,200 @@
+package eval
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// the formats a Report can be written in
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+)
+
+// Report holds the results of a suite.
+type Report struct {
+	Suite   string   `json:"suite"`
+	Models  []string `json:"models"`
+	Results []Result `json:"results"`
+	// NotRun is the number of results left out because the run was
+	// interrupted before they started.
+	NotRun int `json:"not_run,omitempty"`
+}
+
+// Summary is the pass rate of a model.
+type Summary struct {
+	Model    string  `json:"model"`
+	Passed   int     `json:"passed"`
+	Total    int     `json:"total"`
+	PassRate float64 `json:"pass_rate"`
+}
+
+// Summaries returns the pass rate of every model, in the order of Models.
+func (r *Report) Summaries() []Summary {
+	summaries := make([]Summary, len(r.Models))
+	index := map[string]int{}
+	for i, model := range r.Models {
+		summaries[i].Model = model
+		index[model] = i
+	}
+	for _, result := range r.Results {
+		summary := &summaries[index[result.Model]]
+		summary.Total++
+		if result.Passed {
+			summary.Passed++
+		}
+	}
+	for i := range summaries {
+		if summaries[i].Total > 0 {
+			summaries[i].PassRate = float64(summaries[i].Passed) / float64(summaries[i].Total)
+		}
+	}
+	return summaries
+}
+
+// Failed returns the number of results that did not pass.
+func (r *Report) Failed() int {
+	failed := 0
+	for _, result := range r.Results {
+		if !result.Passed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Write writes the report to w in format.
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "", FormatText:
+		return r.WriteText(w)
+	case FormatJSON:
+		return r.WriteJSON(w)
+	case FormatJUnit:
+		return r.WriteJUnit(w)
+	default:
+		return fmt.Errorf("unknown format %q: expected %s, %s or %s", format, FormatText, FormatJSON, FormatJUnit)
+	}
+}
+
+// WriteText writes every result with the reasons of its failures, followed
+// by the pass rate of every model.
+func (r *Report) WriteText(w io.Writer) error {
+	for _, result := range r.Results {
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "%s  %s  [%s]\n", status, result.Case, result.Model)
+		if result.Error != "" {
+			fmt.Fprintf(w, "      error: %s\n", result.Error)
+		}
+		for _, assertion := range result.Assertions {
+			if !assertion.Passed {
+				fmt.Fprintf(w, "      %s: %s\n", assertion.Kind, assertion.Message)
+			}
+		}
+	}
+
+	if r.NotRun > 0 {
+		fmt.Fprintf(w, "\n%d results were not run\n", r.NotRun)
+	}
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "MODEL\tPASSED\tPASS RATE\n")
+	for _, summary := range r.Summaries() {
+		fmt.Fprintf(tw, "%s\t%d/%d\t%.0f%%\n", summary.Model, summary.Passed, summary.Total, summary.PassRate*100)
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the results and the summaries as JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		*Report
+		Summary []Summary `json:"summary"`
+	}{r, r.Summaries()})
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Name    string       `xml:"name,attr"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     float64     `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML with a test suite for every
+// model. Requests that fail are errors, and answers that fail assertions
+// are failures.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suites := junitSuites{Name: r.Suite}
+	index := map[string]int{}
+	for i, model := range r.Models {
+		suites.Suites = append(suites.Suites, junitSuite{Name: model})
+		index[model] = i
+	}
+
+	for _, result := range r.Results {
+		suite := &suites.Suites[index[result.Model]]
+		seconds := result.Duration.Seconds()
+		testCase := junitCase{Name: result.Case, ClassName: r.Suite + "." + result.Model, Time: seconds, SystemOut: result.Output}
+		switch {
+		case result.Error != "":
+			testCase.Error = &junitMessage{Message: result.Error}
+			suite.Errors++
+		case !result.Passed:
+			var reasons []string
+			for _, assertion := range result.Assertions {
+				if !assertion.Passed {
+					reasons = append(reasons, assertion.Kind+": "+assertion.Message)
+				}
+			}
+			testCase.Failure = &junitMessage{Message: reasons[0], Body: strings.Join(reasons, "\n")}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.Time += seconds
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,132 @@
+package eval
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/prompt"
+	"github.com/yiblet/hlp/session"
+)
+
+// DefaultConcurrency is the number of cases a Runner runs at once if none is
+// set.
+const DefaultConcurrency = 4
+
+// Runner runs suites.
+type Runner struct {
+	// Streamer answers the cases and judge assertions. Models are passed
+	// to it as they are named in the suite.
+	Streamer chat.Streamer
+	// Prompts is the library the prompts of cases are read from.
+	Prompts *prompt.Library
+	// Concurrency is the number of cases run at once, DefaultConcurrency
+	// if zero.
+	Concurrency int
+	// OnResult, if set, is called with every result as soon as it is
+	// known, from a single goroutine at a time.
+	OnResult func(Result)
+}
+
+// Result is the outcome of a case for a model.
+type Result struct {
+	Case       string            `json:"case"`
+	Model      string            `json:"model"`
+	Passed     bool              `json:"passed"`
+	Output     string            `json:"output"`
+	Error      string            `json:"error,omitempty"`
+	Assertions []AssertionResult `json:"assertions,omitempty"`
+	Duration   time.Duration     `json:"duration_ns"`
+}
+
+// Run runs every case of suite against models, or the suite's models if
+// there are none. The results are ordered by case, then by model. Once ctx
+// is done no more cases are started, and the report leaves them out.
+func (r *Runner) Run(ctx context.Context, suite *Suite, models []string) (*Report, error) {
+	if len(models) == 0 {
+		models = suite.Models
+	}
+	if len(models) == 0 {
+		return nil, &InvalidSuiteError{Path: suite.Name, Err: errNoModels}
+	}
+	judgeModel := suite.Judge
+	if judgeModel == "" {
+		judgeModel = models[0]
+	}
+	judge := &judge{streamer: r.Streamer, model: judgeModel}
+
+	concurrency := r.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	report := &Report{Suite: suite.Name, Models: models, Results: make([]Result, len(suite.Cases)*len(models))}
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, concurrency)
+	)
+	for i := range suite.Cases {
+		for j, model := range models {
+			wg.Add(1)
+			go func(idx int, c *Case, model string) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				if ctx.Err() != nil {
+					return
+				}
+
+				result := r.runCase(ctx, suite, c, model, judge)
+				mu.Lock()
+				defer mu.Unlock()
+				report.Results[idx] = result
+				if r.OnResult != nil {
+					r.OnResult(result)
+				}
+			}(i*len(models)+j, &suite.Cases[i], model)
+		}
+	}
+	wg.Wait()
+
+	results := report.Results[:0]
+	for _, result := range report.Results {
+		if result.Model == "" {
+			report.NotRun++
+			continue
+		}
+		results = append(results, result)
+	}
+	report.Results = results
+	return report, ctx.Err()
+}
+
+func (r *Runner) runCase(ctx context.Context, suite *Suite, c *Case, model string, judge *judge) Result {
+	result := Result{Case: c.Name, Model: model}
+	start := time.Now()
+
+	messages, err := suite.messages(c, r.Prompts)
+	if err != nil {
+		result.Error = err.Error()
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	conversation := &session.Conversation{Streamer: r.Streamer, Model: model, Messages: messages}
+	result.Output, err = conversation.Generate(ctx, nil)
+	if err != nil {
+		result.Error = err.Error()
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	result.Passed = true
+	for i := range c.Assert {
+		assertion := c.Assert[i].check(ctx, result.Output, judge)
+		result.Assertions = append(result.Assertions, assertion)
+		result.Passed = result.Passed && assertion.Passed
+	}
+	result.Duration = time.Since(start)
+	return result
+}
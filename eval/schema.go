@@ -0,0 +1,318 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// schemaKeywords are the keywords validateSchema supports, which cover the
+// shapes of structured answers. The annotations are accepted and ignored.
+var schemaKeywords = map[string]bool{
+	"type": true, "enum": true, "const": true, "properties": true, "required": true,
+	"additionalProperties": true, "items": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "pattern": true, "minimum": true, "maximum": true,
+	// annotations
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true,
+}
+
+// checkSchema returns an error if schema uses a keyword validateSchema does
+// not support, or gives a keyword a value it cannot use, so that a suite
+// never passes an answer it did not check. path is the location of schema
+// in the suite, for error messages.
+func checkSchema(schema map[string]any, path string) error {
+	keywords := make([]string, 0, len(schema))
+	for keyword := range schema {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	for _, keyword := range keywords {
+		if !schemaKeywords[keyword] {
+			return fmt.Errorf("%s: unsupported keyword %q", path, keyword)
+		}
+	}
+
+	if types, ok := schema["type"]; ok && !validType(types) {
+		return fmt.Errorf("%s: type must be one of %s, or a list of them", path, strings.Join(jsonTypes, ", "))
+	}
+	if enum, ok := schema["enum"]; ok {
+		if _, ok := enum.([]any); !ok {
+			return fmt.Errorf("%s: enum must be a list", path)
+		}
+	}
+	if required, ok := schema["required"]; ok {
+		names, ok := required.([]any)
+		if !ok {
+			return fmt.Errorf("%s: required must be a list of property names", path)
+		}
+		for _, name := range names {
+			if _, ok := name.(string); !ok {
+				return fmt.Errorf("%s: required must be a list of property names", path)
+			}
+		}
+	}
+	for _, keyword := range []string{"minItems", "maxItems", "minLength", "maxLength", "minimum", "maximum"} {
+		if value, ok := schema[keyword]; ok {
+			if _, ok := number(value); !ok {
+				return fmt.Errorf("%s: %s must be a number", path, keyword)
+			}
+		}
+	}
+	if additional, ok := schema["additionalProperties"]; ok {
+		if _, ok := additional.(bool); !ok {
+			return fmt.Errorf("%s: additionalProperties must be true or false", path)
+		}
+	}
+	if pattern, ok := schema["pattern"]; ok {
+		pattern, ok := pattern.(string)
+		if !ok {
+			return fmt.Errorf("%s: pattern must be a string", path)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", path, err)
+		}
+	}
+	if items, ok := schema["items"]; ok {
+		items, ok := items.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: items must be a schema", path)
+		}
+		if err := checkSchema(items, path+".items"); err != nil {
+			return err
+		}
+	}
+	if properties, ok := schema["properties"]; ok {
+		properties, ok := properties.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: properties must be an object", path)
+		}
+		names := make([]string, 0, len(properties))
+		for name := range properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := properties[name].(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s.properties.%s: must be a schema", path, name)
+			}
+			if err := checkSchema(property, path+".properties."+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonTypes are the types a schema can name.
+var jsonTypes = []string{"null", "boolean", "object", "array", "number", "string", "integer"}
+
+// validType reports whether types is a JSON type or a list of them.
+func validType(types any) bool {
+	switch types := types.(type) {
+	case string:
+		for _, name := range jsonTypes {
+			if types == name {
+				return true
+			}
+		}
+	case []any:
+		for _, t := range types {
+			if _, ok := t.(string); !ok || !validType(t) {
+				return false
+			}
+		}
+		return len(types) > 0
+	}
+	return false
+}
+
+// validateSchema checks value against a JSON schema that passed
+// checkSchema. path is the location of value in the answer, for error
+// messages.
+func validateSchema(schema map[string]any, value any, path string) error {
+	if types, ok := schema["type"]; ok {
+		if !matchesType(types, value) {
+			return fmt.Errorf("%s: expected %v, got %s", path, types, jsonType(value))
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(normalize(allowed), value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+		}
+	}
+	if constant, ok := schema["const"]; ok && !reflect.DeepEqual(normalize(constant), value) {
+		return fmt.Errorf("%s: expected %v", path, constant)
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		return validateObject(schema, value, path)
+	case []any:
+		if n, ok := number(schema["minItems"]); ok && float64(len(value)) < n {
+			return fmt.Errorf("%s: expected at least %v items", path, n)
+		}
+		if n, ok := number(schema["maxItems"]); ok && float64(len(value)) > n {
+			return fmt.Errorf("%s: expected at most %v items", path, n)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				if err := validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(value))
+		if n, ok := number(schema["minLength"]); ok && length < n {
+			return fmt.Errorf("%s: expected at least %v characters", path, n)
+		}
+		if n, ok := number(schema["maxLength"]); ok && length > n {
+			return fmt.Errorf("%s: expected at most %v characters", path, n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			regex, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern: %w", path, err)
+			}
+			if !regex.MatchString(value) {
+				return fmt.Errorf("%s: does not match %q", path, pattern)
+			}
+		}
+	case float64:
+		if n, ok := number(schema["minimum"]); ok && value < n {
+			return fmt.Errorf("%s: expected at least %v", path, n)
+		}
+		if n, ok := number(schema["maximum"]); ok && value > n {
+			return fmt.Errorf("%s: expected at most %v", path, n)
+		}
+	}
+	return nil
+}
+
+func validateObject(schema map[string]any, value map[string]any, path string) error {
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, exists := value[name]; !exists {
+					return fmt.Errorf("%s: missing property %q", path, name)
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, ok := properties[name].(map[string]any)
+		if !ok {
+			if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+				return fmt.Errorf("%s: unexpected property %q", path, name)
+			}
+			continue
+		}
+		if err := validateSchema(property, value[name], path+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchesType reports whether value has the type, or one of the list of
+// types, of a schema.
+func matchesType(types any, value any) bool {
+	switch types := types.(type) {
+	case string:
+		actual := jsonType(value)
+		if types == "number" && actual == "integer" {
+			return true
+		}
+		return types == actual
+	case []any:
+		for _, t := range types {
+			if matchesType(t, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func number(value any) (float64, bool) {
+	switch value := normalize(value).(type) {
+	case float64:
+		return value, true
+	}
+	return 0, false
+}
+
+// normalize converts a value decoded from YAML to the types encoding/json
+// decodes into, so that schemas from YAML compare with JSON answers.
+func normalize(value any) any {
+	switch value := value.(type) {
+	case int:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case uint64:
+		return float64(value)
+	case float32:
+		return float64(value)
+	case map[string]any:
+		normalized := make(map[string]any, len(value))
+		for k, v := range value {
+			normalized[k] = normalize(v)
+		}
+		return normalized
+	case map[any]any:
+		normalized := make(map[string]any, len(value))
+		for k, v := range value {
+			normalized[strings.TrimSpace(fmt.Sprint(k))] = normalize(v)
+		}
+		return normalized
+	case []any:
+		normalized := make([]any, len(value))
+		for i, v := range value {
+			normalized[i] = normalize(v)
+		}
+		return normalized
+	}
+	return value
+}
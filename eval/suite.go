@@ -0,0 +1,184 @@
+// Package eval runs suites of test cases against models and checks their
+// answers with assertions, for regression testing prompts.
+//
+// A suite is a YAML file:
+//
+//	name: bash prompt
+//	models: [gpt-4o-mini, gpt-4o]
+//	judge: gpt-4o
+//	cases:
+//	  - name: list files
+//	    prompt: bash
+//	    input: list the files in {{.dir}} modified today
+//	    vars: {dir: /tmp}
+//	    assert:
+//	      - bash: true
+//	      - contains: find
+//	      - judge: the command only lists files and changes nothing
+//	  - name: review
+//	    chat: review.chat
+//	    assert:
+//	      - json_schema: {type: object, required: [issues]}
+package eval
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/prompt"
+	"github.com/yiblet/hlp/session"
+	"gopkg.in/yaml.v3"
+)
+
+// Suite is a set of test cases run against models.
+type Suite struct {
+	Name string `yaml:"name"`
+	// Models are the models every case is run against.
+	Models []string `yaml:"models"`
+	// Judge is the model that grades judge assertions, the first of Models
+	// if empty.
+	Judge string `yaml:"judge"`
+	Cases []Case `yaml:"cases"`
+
+	// dir is the directory chat files are relative to.
+	dir string
+}
+
+// Case is a test case. Its messages are either a chat file or Input sent as
+// a user message, with the named Prompt as the system prompt.
+type Case struct {
+	Name   string `yaml:"name"`
+	Chat   string `yaml:"chat"`
+	Prompt string `yaml:"prompt"`
+	Input  string `yaml:"input"`
+	// Vars are the template variables of the chat file, or of the prompt
+	// and input. The messages are only rendered as templates if there are
+	// any.
+	Vars   map[string]string `yaml:"vars"`
+	Assert []Assertion       `yaml:"assert"`
+}
+
+// Load reads the suite in the YAML file at path.
+func Load(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var suite Suite
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&suite); err != nil {
+		return nil, &InvalidSuiteError{Path: path, Err: err}
+	}
+	suite.dir = filepath.Dir(path)
+	if suite.Name == "" {
+		suite.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := suite.validate(); err != nil {
+		return nil, &InvalidSuiteError{Path: path, Err: err}
+	}
+	return &suite, nil
+}
+
+func (s *Suite) validate() error {
+	if len(s.Cases) == 0 {
+		return fmt.Errorf("no cases")
+	}
+	names := map[string]bool{}
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("case %d", i+1)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("duplicate case %q", c.Name)
+		}
+		names[c.Name] = true
+
+		switch {
+		case c.Chat != "" && (c.Input != "" || c.Prompt != ""):
+			return fmt.Errorf("case %q: a chat file cannot be combined with a prompt or input", c.Name)
+		case c.Chat == "" && c.Input == "":
+			return fmt.Errorf("case %q: needs a chat file or an input", c.Name)
+		case len(c.Assert) == 0:
+			return fmt.Errorf("case %q: has no assertions", c.Name)
+		}
+		for j := range c.Assert {
+			if err := c.Assert[j].validate(); err != nil {
+				return fmt.Errorf("case %q: assertion %d: %w", c.Name, j+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// messages builds the request of the case.
+func (s *Suite) messages(c *Case, library *prompt.Library) ([]chat.Message, error) {
+	if c.Chat != "" {
+		return s.chatMessages(c)
+	}
+
+	content, err := c.render(c.Input)
+	if err != nil {
+		return nil, fmt.Errorf("cannot render input: %w", err)
+	}
+	var system string
+	if c.Prompt != "" {
+		if system, err = library.Get(c.Prompt); err != nil {
+			return nil, err
+		}
+		if system, err = c.render(system); err != nil {
+			return nil, fmt.Errorf("cannot render prompt %s: %w", c.Prompt, err)
+		}
+	}
+	return session.Question(system, content), nil
+}
+
+// chatMessages reads the chat file of the case. A trailing assistant reply
+// is dropped so that it is generated by the model under test.
+func (s *Suite) chatMessages(c *Case) ([]chat.Message, error) {
+	path := c.Chat
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := session.PrepareChat(string(data), session.ChatOptions{
+		Render: func(messages []chat.Message) ([]chat.Message, error) {
+			for i := range messages {
+				content, err := c.render(messages[i].Content)
+				if err != nil {
+					return nil, fmt.Errorf("cannot render %s: %w", c.Chat, err)
+				}
+				messages[i].Content = content
+			}
+			return messages, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	messages := file.Messages
+	if n := len(messages); n > 0 && messages[n-1].Role == "assistant" {
+		messages = messages[:n-1]
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("chat file %s has no messages", c.Chat)
+	}
+	return messages, nil
+}
+
+func (c *Case) render(text string) (string, error) {
+	if len(c.Vars) == 0 {
+		return text, nil
+	}
+	return prompt.Render(text, prompt.Vars(c.Vars))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yiblet/hlp/eval"
+	"github.com/yiblet/hlp/profile"
+)
+
+type evalCmd struct {
+	Suite       string   `arg:"positional,required" help:"the suite, a YAML file of test cases"`
+	Models      []string `arg:"--model,-m,separate" help:"run the cases against this model instead of the suite's models, pass it once for every model"`
+	Format      string   `arg:"--format,-f" default:"text" help:"format of the report: text, json or junit"`
+	Output      string   `arg:"--output,-o" help:"write the report to this file instead of stdout"`
+	Concurrency int      `arg:"--concurrency,-j" default:"4" help:"the number of cases run at once"`
+}
+
+func (args *evalCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	// fail on a typo before running the whole suite
+	if err := (&eval.Report{}).Write(io.Discard, args.Format); err != nil {
+		return err
+	}
+
+	suite, err := eval.Load(args.Suite)
+	if err != nil {
+		return err
+	}
+	client, err := config.Client()
+	if err != nil {
+		return err
+	}
+
+	runner := &eval.Runner{
+		Streamer:    client,
+		Prompts:     config.Prompts(),
+		Concurrency: args.Concurrency,
+	}
+	if args.Output != "" || args.Format != eval.FormatText {
+		// the report is not on the terminal, so show the progress there
+		runner.OnResult = func(result eval.Result) {
+			status := colorGreen + "PASS" + colorReset
+			if !result.Passed {
+				status = colorRed + "FAIL" + colorReset
+			}
+			fmt.Fprintf(os.Stderr, "%s  %s  [%s]\n", status, result.Case, result.Model)
+		}
+	}
+
+	var report *eval.Report
+	err = interrupts.run(ctx, func(ctx context.Context) error {
+		var err error
+		report, err = runner.Run(ctx, suite, args.Models)
+		return err
+	})
+	if report == nil {
+		return err
+	}
+	if err != nil && !errors.Is(err, errInterrupted) {
+		return err
+	}
+
+	if args.Output != "" {
+		if err := writeFileAtomic(args.Output, func(w io.Writer) error {
+			return report.Write(w, args.Format)
+		}); err != nil {
+			return err
+		}
+	} else if err := report.Write(os.Stdout, args.Format); err != nil {
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if failed := report.Failed(); failed > 0 {
+		return fmt.Errorf("%d of %d results failed", failed, len(report.Results))
+	}
+	return nil
+}
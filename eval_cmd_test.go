@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/profile"
+)
+
+func TestEvalCmd(t *testing.T) {
+	dir := t.TempDir()
+	suite := filepath.Join(dir, "suite.yaml")
+	require.NoError(t, os.WriteFile(suite, []byte(`
+models: [gpt-4o-mini]
+cases:
+  - name: echo
+    input: echo {{.word}}
+    vars: {word: hello}
+    assert:
+      - contains: hello
+      - bash: true
+  - name: missing
+    input: hello
+    assert:
+      - contains: goodbye
+`), 0644))
+
+	t.Run("text", func(t *testing.T) {
+		args := &evalCmd{Suite: suite, Format: "text", Concurrency: 2}
+		var err error
+		output := captureStdout(t, func() {
+			err = args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{}))
+		})
+		assert.EqualError(t, err, "1 of 2 results failed")
+		assert.Contains(t, output, "PASS  echo  [gpt-4o-mini]")
+		assert.Contains(t, output, "FAIL  missing  [gpt-4o-mini]")
+		assert.Contains(t, output, `contains: does not contain "goodbye"`)
+	})
+
+	t.Run("junit file", func(t *testing.T) {
+		out := filepath.Join(dir, "report.xml")
+		args := &evalCmd{Suite: suite, Format: "junit", Output: out, Models: []string{"a", "b"}}
+		err := args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{}))
+		assert.EqualError(t, err, "2 of 4 results failed")
+
+		report, err := os.ReadFile(out)
+		require.NoError(t, err)
+		assert.Contains(t, string(report), `<testsuite name="b" tests="2" failures="1"`)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		args := &evalCmd{Suite: suite, Format: "yaml"}
+		assert.Error(t, args.Execute(context.Background(), newFakeConfig(profile.FakeConfig{})))
+	})
+}
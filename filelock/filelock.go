@@ -0,0 +1,62 @@
+// Package filelock serializes writes to a file across processes.
+package filelock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Lock takes an exclusive lock for the file at path, waiting for other
+// processes to release it first. wait, if set, is called before waiting. The
+// lock is held on a separate hidden lock file since the file itself is often
+// replaced on every write. The lock is released automatically if the process
+// dies, and the lock file is removed by the returned unlock function.
+func Lock(path string, wait func()) (func() error, error) {
+	if wait == nil {
+		wait = func() {}
+	}
+	lockPath := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".lock")
+	for {
+		file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create lock file: %w", err)
+		}
+
+		if err := lockFileHandle(file, wait); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("cannot lock %s: %w", path, err)
+		}
+
+		// the process we waited for may have removed the lock file, in
+		// which case others lock a new one and ours does not count
+		if !sameFile(file, lockPath) {
+			unlockFileHandle(file)
+			file.Close()
+			continue
+		}
+
+		return func() error {
+			// removed while it is still locked, so that nobody locks it in
+			// between. Windows does not remove open files, so it is removed
+			// after closing it there.
+			removed := os.Remove(lockPath) == nil
+			err := unlockFileHandle(file)
+			file.Close()
+			if !removed {
+				os.Remove(lockPath)
+			}
+			return err
+		}, nil
+	}
+}
+
+// sameFile reports whether file is still the file at path.
+func sameFile(file *os.File, path string) bool {
+	opened, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	return err == nil && os.SameFile(opened, current)
+}
@@ -0,0 +1,54 @@
+package filelock
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "c.chat")
+
+	t.Run("removes the lock file", func(t *testing.T) {
+		unlock, err := Lock(path, nil)
+		require.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dir, ".c.chat.lock"))
+		require.NoError(t, unlock())
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("excludes while lock files come and go", func(t *testing.T) {
+		var holders, overlaps atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 10; j++ {
+					unlock, err := Lock(path, nil)
+					if !assert.NoError(t, err) {
+						return
+					}
+					if holders.Add(1) > 1 {
+						overlaps.Add(1)
+					}
+					time.Sleep(time.Millisecond)
+					holders.Add(-1)
+					assert.NoError(t, unlock())
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Zero(t, overlaps.Load())
+	})
+}
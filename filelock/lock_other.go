@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package filelock
+
+import "os"
+
+// file locking is not supported on this platform, so concurrent writes are
+// not prevented.
+func lockFileHandle(file *os.File, wait func()) error { return nil }
+
+func unlockFileHandle(file *os.File) error { return nil }
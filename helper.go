@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
 
 	"github.com/alexflint/go-arg"
+	"mvdan.cc/sh/v3/shell"
 )
 
 func writeHelp(config any, output io.Writer) error {
@@ -55,3 +60,74 @@ func copyFile(src, dst string) error {
 
 	return nil
 }
+
+// openEditor opens path in the user's editor and waits for it to exit. The
+// editor is taken from $VISUAL or $EDITOR and falls back to vi. The editor
+// command may contain arguments, e.g. "code --wait", which are split like a
+// shell would without running one, so it works the same on Windows. An
+// editor that names an existing file is run as is, so its path needs no
+// quoting.
+func openEditor(ctx context.Context, path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	words := []string{editor}
+	if _, err := os.Stat(editor); err != nil {
+		words, err = shell.Fields(editor, nil)
+		if err != nil {
+			return fmt.Errorf("invalid editor %q: %w", editor, err)
+		}
+		if len(words) == 0 {
+			return fmt.Errorf("invalid editor %q", editor)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, words[0], append(words[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// writeFileAtomic replaces the file at path with the output of write. The
+// output goes to a temporary file in the same directory which is renamed over
+// path once it is complete, so path is never left partially written. The
+// permissions of an existing file are kept, and a symlink at path is kept
+// pointing to the replaced file.
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	path = resolveLink(path)
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmpFile.Name()
+	defer os.Remove(tmpName) // fails harmlessly once the file is renamed
+	defer tmpFile.Close()
+
+	if err := write(tmpFile); err != nil {
+		return err
+	}
+
+	// Sync the written content to the disk before it replaces the original
+	if err := tmpFile.Sync(); err != nil {
+		return err
+	}
+	if err := tmpFile.Chmod(mode); err != nil {
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpName, path)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEditor installs a shell script as $EDITOR, with any extra arguments.
+func fakeEditor(t *testing.T, script string, args string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake editor is a shell script")
+	}
+	path := filepath.Join(t.TempDir(), "my editor")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755))
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", `"`+path+`"`+args)
+}
+
+func TestOpenEditor(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+
+	fakeEditor(t, `printf '%s|' "$@" > "$2"`, " --wait")
+	require.NoError(t, openEditor(context.Background(), file))
+	assert.Equal(t, "--wait|"+file+"|", readChatFile(t, file))
+
+	t.Setenv("EDITOR", `"unterminated`)
+	assert.ErrorContains(t, openEditor(context.Background(), file), "invalid editor")
+}
+
+func TestWriteFileAtomicSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.chat")
+	link := filepath.Join(dir, "link.chat")
+	require.NoError(t, os.WriteFile(target, []byte("old"), 0600))
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+
+	require.NoError(t, writeFileAtomic(link, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+		return err
+	}))
+
+	info, err := os.Lstat(link)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink, "the link is kept")
+	assert.Equal(t, "new", readChatFile(t, target))
+	info, err = os.Stat(target)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+)
+
+// errInterrupted is returned when a generation is canceled by an interrupt.
+var errInterrupted = errors.New("interrupted")
+
+// truncationMarker is appended to replies that were interrupted before they
+// were complete.
+const truncationMarker = "\n[reply interrupted by the user]\n"
+
+// interruptHandler cancels in-flight generations on Ctrl-C instead of killing
+// the process. The first interrupt cancels the generation, a second one while
+// the generation is still running exits.
+type interruptHandler struct {
+	notify func(chan<- os.Signal)
+	stop   func(chan<- os.Signal)
+	exit   func()
+}
+
+var interrupts = &interruptHandler{
+	notify: func(ch chan<- os.Signal) { signal.Notify(ch, os.Interrupt) },
+	stop:   func(ch chan<- os.Signal) { signal.Stop(ch) },
+	exit:   func() { os.Exit(130) },
+}
+
+// run calls fn with a context that is canceled on the first interrupt. It
+// returns errInterrupted if fn was canceled by an interrupt.
+func (h *interruptHandler) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	sigCh := make(chan os.Signal, 1)
+	h.notify(sigCh)
+	defer h.stop(sigCh)
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-sigCh:
+			cancel(errInterrupted)
+		case <-done:
+			return
+		}
+
+		select {
+		case <-sigCh:
+			h.exit()
+		case <-done:
+		}
+	}()
+
+	err := fn(ctx)
+	close(done)
+	<-exited
+
+	if errors.Is(context.Cause(ctx), errInterrupted) {
+		return errInterrupted
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestInterruptHandler returns a handler whose interrupts are injected
+// through the returned channel.
+func newTestInterruptHandler(exits *atomic.Int32) (*interruptHandler, <-chan chan<- os.Signal) {
+	registered := make(chan chan<- os.Signal, 1)
+	return &interruptHandler{
+		notify: func(ch chan<- os.Signal) { registered <- ch },
+		stop:   func(ch chan<- os.Signal) {},
+		exit:   func() { exits.Add(1) },
+	}, registered
+}
+
+func TestInterruptHandler(t *testing.T) {
+	t.Parallel()
+
+	t.Run("completes without interrupt", func(t *testing.T) {
+		t.Parallel()
+		var exits atomic.Int32
+		handler, _ := newTestInterruptHandler(&exits)
+
+		fnErr := errors.New("fn error")
+		err := handler.run(context.Background(), func(ctx context.Context) error {
+			return fnErr
+		})
+		assert.ErrorIs(t, err, fnErr)
+		assert.Equal(t, int32(0), exits.Load())
+	})
+
+	t.Run("first interrupt cancels", func(t *testing.T) {
+		t.Parallel()
+		var exits atomic.Int32
+		handler, registered := newTestInterruptHandler(&exits)
+
+		err := handler.run(context.Background(), func(ctx context.Context) error {
+			(<-registered) <- os.Interrupt
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+				return errors.New("context was not canceled")
+			}
+		})
+		assert.ErrorIs(t, err, errInterrupted)
+		assert.Equal(t, int32(0), exits.Load())
+	})
+
+	t.Run("second interrupt exits", func(t *testing.T) {
+		t.Parallel()
+		var exits atomic.Int32
+		handler, registered := newTestInterruptHandler(&exits)
+
+		err := handler.run(context.Background(), func(ctx context.Context) error {
+			sigCh := <-registered
+			sigCh <- os.Interrupt
+			<-ctx.Done()
+			// keep running after the cancellation like a stream that is
+			// slow to stop
+			sigCh <- os.Interrupt
+			assert.Eventually(t, func() bool { return exits.Load() == 1 }, 5*time.Second, time.Millisecond)
+			return ctx.Err()
+		})
+		assert.ErrorIs(t, err, errInterrupted)
+	})
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/yiblet/hlp/filelock"
+)
+
+// lockFile takes an exclusive lock for the file at path, waiting for other
+// processes to release it first, see filelock.Lock. A symlink is locked as
+// the file it points to, which is the one writes replace.
+func lockFile(path string) (func() error, error) {
+	path = resolveLink(path)
+	return filelock.Lock(path, func() {
+		fmt.Fprintf(os.Stderr, "waiting for another hlp process to finish writing %s\n", path)
+	})
+}
+
+// resolveLink returns the file path refers to if it is a symlink, so that
+// writes replace the target instead of the link. path is returned as is if
+// it cannot be resolved, e.g. because it does not exist yet.
+func resolveLink(path string) string {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		return target
+	}
+	return path
+}
@@ -6,43 +6,152 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/alexflint/go-arg"
+	"github.com/yiblet/hlp/profile"
+	"github.com/yiblet/hlp/project"
 )
 
 type mainCmd struct {
-	Ask        *askCmd    `arg:"subcommand"`
-	Config     *configCmd `arg:"subcommand"`
-	Chat       *chatCmd   `arg:"subcommand"`
-	ConfigName string     `arg:"-c,--config,env:HLP_CONFIG" help:"name of the configuration set"`
-	Debug      bool       `arg:"-d,--debug" help:"enable debug mode"`
+	Ask         *askCmd        `arg:"subcommand"`
+	Config      *configCmd     `arg:"subcommand"`
+	Chat        *chatCmd       `arg:"subcommand"`
+	Prompts     *promptsCmd    `arg:"subcommand"`
+	Cache       *cacheCmd      `arg:"subcommand"`
+	Profile     *profileCmd    `arg:"subcommand"`
+	Compare     *compareCmd    `arg:"subcommand" help:"ask several models the same question and compare their answers"`
+	Eval        *evalCmd       `arg:"subcommand" help:"run a suite of test cases against models and check the answers"`
+	Batch       *batchCmd      `arg:"subcommand" help:"answer many requests from a JSON lines file concurrently"`
+	ConfigName  string         `arg:"-c,--config,env:HLP_CONFIG" help:"name of the configuration set"`
+	Debug       bool           `arg:"-d,--debug" help:"enable debug mode, debug output is written to stderr"`
+	DebugLog    string         `arg:"--debug-log" help:"write debug output to this file instead, implies --debug"`
+	DebugFormat string         `arg:"--debug-format" default:"text" help:"format of the debug output: text or json"`
+	Timeout     *time.Duration `arg:"--timeout" help:"total timeout of a request, 0 disables it [default: 2m]"`
+	IdleTimeout *time.Duration `arg:"--idle-timeout" help:"cancel a request if no token arrives for this long, 0 disables it"`
+	Proxy       string         `arg:"--proxy" help:"url of the proxy to send requests through"`
+	CACerts     []string       `arg:"--ca-cert,separate" help:"additional CA certificate file in PEM format"`
+	Headers     []string       `arg:"--header,-H,separate" help:"additional HTTP header as 'Name: value'"`
+	Record      string         `arg:"--record" help:"record every HTTP exchange into this directory"`
+	Replay      string         `arg:"--replay" help:"answer requests from the exchanges recorded in this directory instead of the network"`
+	NoCache     bool           `arg:"--no-cache" help:"do not answer requests from the response cache"`
+	NoProject   bool           `arg:"--no-project" help:"ignore the .hlp.toml or .hlp.json of the current project"`
 }
 
-func (args *mainCmd) SetupConfig() (config, error) {
-	var err error
-	cfg, err := ReadConfig(args.ConfigName, args.Debug)
+func (args *mainCmd) SetupConfig() (*profile.Profile, error) {
+	cfg, err := profile.Read(args.ConfigName)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching configs: %w", err)
+	}
 
+	cfg.Debug = args.Debug || args.DebugLog != ""
+	if args.DebugLog != "" {
+		cfg.DebugLog, err = profile.OpenDebugLog(args.DebugLog, args.DebugFormat)
+	} else if cfg.Debug {
+		cfg.DebugLog, err = profile.NewDebugLog(os.Stderr, args.DebugFormat)
+	}
 	if err != nil {
-		return config{}, fmt.Errorf("failed fetching configs: %w", err)
+		return nil, err
+	}
+
+	if !args.NoProject {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Project, err = project.Find(wd); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := args.applyFlags(cfg); err != nil {
+		return nil, err
 	}
 	return cfg, nil
 }
 
+// applyFlags records the settings passed on the command line, which take
+// precedence over every other source once the config is resolved.
+func (args *mainCmd) applyFlags(cfg *profile.Profile) error {
+	flags := map[string]string{}
+	if args.Timeout != nil {
+		if *args.Timeout < 0 {
+			return fmt.Errorf("--timeout cannot be negative")
+		}
+		flags["timeout"] = args.Timeout.String()
+	}
+	if args.IdleTimeout != nil {
+		if *args.IdleTimeout < 0 {
+			return fmt.Errorf("--idle-timeout cannot be negative")
+		}
+		flags["idle_timeout"] = args.IdleTimeout.String()
+	}
+	if args.Proxy != "" {
+		flags["proxy"] = args.Proxy
+	}
+	if args.Record != "" && args.Replay != "" {
+		return fmt.Errorf("cannot both --record and --replay")
+	}
+	cfg.RecordDir = args.Record
+	cfg.ReplayDir = args.Replay
+	if args.NoCache {
+		flags["cache.enabled"] = "false"
+	}
+	if len(args.CACerts) > 0 {
+		// a path may contain a comma, so the list is never joined
+		cfg.ListFlags = map[string][]string{"ca_certs": args.CACerts}
+	}
+
+	for _, header := range args.Headers {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.Contains(name, ".") {
+			return fmt.Errorf("invalid header %q: expected 'Name: value'", header)
+		}
+		flags["headers."+name] = strings.TrimSpace(value)
+	}
+	cfg.Flags = flags
+	return nil
+}
+
 func (args *mainCmd) Execute(ctx context.Context) error {
 	config, err := args.SetupConfig()
 	if err != nil {
 		return err
 	}
+	defer config.Close()
 
 	switch {
-	case args.Ask != nil:
-		err = args.Ask.Execute(ctx, &config)
 	case args.Config != nil:
-		err = args.Config.Execute(ctx, &config)
+		return args.Config.Execute(ctx, config)
+	case args.Profile != nil:
+		return args.Profile.Execute(ctx, config)
+	case args.Ask == nil && args.Chat == nil && args.Prompts == nil && args.Cache == nil && args.Compare == nil && args.Eval == nil && args.Batch == nil:
+		return writeHelp(args, os.Stderr)
+	}
+
+	// requests use the effective settings, while config and profile change
+	// the stored ones
+	resolved, err := config.Resolve()
+	if err != nil {
+		return err
+	}
+	switch {
+	case args.Ask != nil:
+		err = args.Ask.Execute(ctx, resolved)
 	case args.Chat != nil:
-		err = args.Chat.Execute(ctx, &config)
-	default:
-		err = writeHelp(args, os.Stderr)
+		err = args.Chat.Execute(ctx, resolved)
+	case args.Prompts != nil:
+		err = args.Prompts.Execute(ctx, resolved)
+	case args.Cache != nil:
+		err = args.Cache.Execute(ctx, resolved)
+	case args.Compare != nil:
+		err = args.Compare.Execute(ctx, resolved)
+	case args.Eval != nil:
+		err = args.Eval.Execute(ctx, resolved)
+	case args.Batch != nil:
+		err = args.Batch.Execute(ctx, resolved)
 	}
 
 	return err
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/profile"
+)
+
+// newFakeConfig returns a config that answers with the fake provider.
+func newFakeConfig(fake profile.FakeConfig) *profile.Profile {
+	return &profile.Profile{Provider: profile.ProviderFake, Fake: &fake}
+}
+
+// captureStdout returns everything fn writes to os.Stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		buf, _ := io.ReadAll(reader)
+		output <- string(buf)
+	}()
+
+	fn()
+	writer.Close()
+	return <-output
+}
@@ -7,3 +7,35 @@ type InvalidRoleError struct {
 }
 
 func (r *InvalidRoleError) Error() string { return fmt.Sprintf("invalid role: %s", r.Role) }
+
+type InvalidAlternativeError struct {
+	Line string
+}
+
+func (e *InvalidAlternativeError) Error() string {
+	return fmt.Sprintf("only assistant replies can have alternatives: %s", e.Line)
+}
+
+type UnpickedAlternativesError struct {
+	Offset int
+}
+
+func (e *UnpickedAlternativesError) Error() string {
+	return "chat file contains alternative replies, pick one before continuing"
+}
+
+type InvalidPartialError struct {
+	Line string
+}
+
+func (e *InvalidPartialError) Error() string {
+	return fmt.Sprintf("only assistant replies can be partial: %s", e.Line)
+}
+
+type PartialReplyError struct {
+	Offset int
+}
+
+func (e *PartialReplyError) Error() string {
+	return "chat file contains a partial reply, regenerate or resume it before continuing"
+}
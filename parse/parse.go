@@ -5,12 +5,27 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/yiblet/hlp/chat"
 )
 
-var boundaryRegexp = regexp.MustCompile(`^---\s*(user|system|assistant)\s*$`)
+var boundaryRegexp = regexp.MustCompile(`^---\s*(user|system|assistant)\s*(?:\[\s*(\d+)\s*(?:/\s*\d+\s*)?\]|\((partial)\))?\s*$`)
+
+// Block is a single role section of a chat file.
+type Block struct {
+	Role    string
+	Content string
+	// Alternative is the 1-based index of the block if it is one of several
+	// alternative assistant replies, and 0 otherwise.
+	Alternative int
+	// Partial is true if the block is an assistant reply that was
+	// interrupted before it was complete.
+	Partial bool
+	// Offset is the byte offset in the file at which the block starts.
+	Offset int
+}
 
 // ParseChatFile parses a chat file with the following format:
 //
@@ -27,52 +42,159 @@ var boundaryRegexp = regexp.MustCompile(`^---\s*(user|system|assistant)\s*$`)
 //
 // valid roles are "system", "assistant", and "user". System can only appear
 // as the first role in the chat log.
+//
+// A file containing alternative assistant replies (see ParseBlocks) cannot be
+// turned into a conversation until one of the alternatives is picked, and
+// results in an *UnpickedAlternativesError.
 func ParseChatFile(file io.Reader) ([]chat.Message, error) {
+	blocks, err := ParseBlocks(file)
+	if err != nil {
+		return nil, err
+	}
+	return Messages(blocks)
+}
+
+// ParseBlocks parses a chat file into its blocks. See ParseChatFile for the
+// file format. In addition, consecutive assistant replies can be marked as
+// alternatives of the same turn with a numbered boundary:
+//
+// --- assistant [1/2]
+// First candidate reply
+// --- assistant [2/2]
+// Second candidate reply
+//
+// and an assistant reply that was interrupted while it was written is marked
+// as partial:
+//
+// --- assistant (partial)
+// The beginning of a rep
+//
+// Blocks without content are dropped, except partial ones.
+func ParseBlocks(file io.Reader) ([]Block, error) {
 	scanner := bufio.NewScanner(file)
-	messages := []chat.Message{}
+	blocks := []Block{}
+
+	// track the byte offset of each line
+	var lineOffset, nextOffset int
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if token != nil {
+			lineOffset = nextOffset
+		}
+		nextOffset += advance
+		return advance, token, err
+	})
 
-	var currentRole string
+	var current Block
 	var currentMessage strings.Builder
 
 	for scanner.Scan() {
 		line := scanner.Text()
 
 		if matches := boundaryRegexp.FindStringSubmatch(strings.ToLower(line)); matches != nil {
-			if currentRole != "" && currentMessage.Len() > 0 {
-				messages = append(messages, chat.Message{
-					Role:    currentRole,
-					Content: currentMessage.String(),
-				})
+			if current.keep(currentMessage.Len()) {
+				current.Content = currentMessage.String()
+				blocks = append(blocks, current)
 				currentMessage.Reset()
 			}
 
-			currentRole = matches[1]
-			if err := ValidateRole(currentRole); err != nil {
+			current = Block{Role: matches[1], Offset: lineOffset}
+			if err := ValidateRole(current.Role); err != nil {
 				return nil, err
 			}
+			if matches[2] != "" {
+				if current.Role != "assistant" {
+					return nil, &InvalidAlternativeError{Line: line}
+				}
+				current.Alternative, _ = strconv.Atoi(matches[2])
+			}
+			if matches[3] != "" {
+				if current.Role != "assistant" {
+					return nil, &InvalidPartialError{Line: line}
+				}
+				current.Partial = true
+			}
 			continue
 		}
 
 		// if there is no role, but there is some sort of content assume
 		// that it's the user talking.
-		if currentRole == "" && strings.TrimSpace(line) != "" {
-			currentRole = "system"
+		if current.Role == "" && strings.TrimSpace(line) != "" {
+			current = Block{Role: "system", Offset: lineOffset}
 		}
-		if currentRole != "" {
+		if current.Role != "" {
 			fmt.Fprintf(&currentMessage, "%s\n", line)
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if current.keep(currentMessage.Len()) {
+		current.Content = currentMessage.String()
+		blocks = append(blocks, current)
+	}
 
-	if currentRole != "" && currentMessage.Len() > 0 {
+	return blocks, nil
+}
+
+// keep reports whether a block with contentLen bytes of content is part of
+// the file. Empty blocks are dropped, except partial ones: a reply that was
+// interrupted before its first token still has to be resumed or
+// regenerated.
+func (b *Block) keep(contentLen int) bool {
+	return b.Role != "" && (contentLen > 0 || b.Partial)
+}
+
+// Messages converts blocks into the messages of a conversation. It returns an
+// *UnpickedAlternativesError if the blocks contain alternative replies and a
+// *PartialReplyError if they contain a partial reply.
+func Messages(blocks []Block) ([]chat.Message, error) {
+	messages := make([]chat.Message, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Alternative != 0 {
+			return nil, &UnpickedAlternativesError{Offset: block.Offset}
+		}
+		if block.Partial {
+			return nil, &PartialReplyError{Offset: block.Offset}
+		}
 		messages = append(messages, chat.Message{
-			Role:    currentRole,
-			Content: currentMessage.String(),
+			Role:    block.Role,
+			Content: block.Content,
 		})
 	}
-
 	return messages, nil
 }
 
+// LastTurn returns the index of the first block of the last turn. Consecutive
+// alternative assistant replies are part of the same turn. It returns
+// len(blocks) if there are no blocks.
+func LastTurn(blocks []Block) int {
+	idx := len(blocks) - 1
+	if idx < 0 {
+		return len(blocks)
+	}
+	for idx > 0 && blocks[idx].Alternative != 0 && blocks[idx-1].Alternative != 0 {
+		idx--
+	}
+	return idx
+}
+
+// FormatBoundary formats the boundary line that starts a block. If total is
+// greater than one the block is marked as alternative idx (1-based) of total.
+func FormatBoundary(role string, idx, total int) string {
+	if total > 1 {
+		return fmt.Sprintf("--- %s [%d/%d]", role, idx, total)
+	}
+	return fmt.Sprintf("--- %s", role)
+}
+
+// FormatPartialBoundary formats the boundary line that starts a partial
+// block.
+func FormatPartialBoundary(role string) string {
+	return fmt.Sprintf("--- %s (partial)", role)
+}
+
 func ValidateRole(role string) error {
 	if role != "system" && role != "assistant" && role != "user" {
 		return &InvalidRoleError{role}
@@ -66,3 +66,74 @@ func TestParseChatFile(t *testing.T) {
 		})
 	}
 }
+
+func TestParseBlocks(t *testing.T) {
+	input := "--- user\nquestion\n\n--- assistant [1/2]\nfirst\n--- Assistant [2/2]\nsecond\n"
+	blocks, err := parse.ParseBlocks(strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Equal(t, []parse.Block{
+		{Role: "user", Content: "question\n\n", Offset: 0},
+		{Role: "assistant", Content: "first\n", Alternative: 1, Offset: 19},
+		{Role: "assistant", Content: "second\n", Alternative: 2, Offset: 45},
+	}, blocks)
+	assert.Equal(t, 1, parse.LastTurn(blocks))
+	assert.Equal(t, 0, parse.LastTurn(blocks[:1]))
+
+	_, err = parse.Messages(blocks)
+	var unpicked *parse.UnpickedAlternativesError
+	assert.ErrorAs(t, err, &unpicked)
+
+	_, err = parse.ParseBlocks(strings.NewReader("--- user [1/2]\nquestion\n"))
+	var invalid *parse.InvalidAlternativeError
+	assert.ErrorAs(t, err, &invalid)
+}
+
+func TestPick(t *testing.T) {
+	input := "--- user\nquestion\n\n--- assistant [1/2]\nfirst\n--- assistant [2/2]\nsecond\n--- user\nfollow up\n"
+
+	output, err := parse.Pick(input, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "--- user\nquestion\n\n--- assistant\nsecond\n--- user\nfollow up\n", output)
+
+	messages, err := parse.ParseChatFile(strings.NewReader(output))
+	assert.NoError(t, err)
+	assert.Equal(t, []chat.Message{
+		{Role: "user", Content: "question\n\n"},
+		{Role: "assistant", Content: "second\n"},
+		{Role: "user", Content: "follow up\n"},
+	}, messages)
+
+	_, err = parse.Pick(input, 3)
+	assert.Error(t, err)
+
+	_, err = parse.Pick(output, 1)
+	assert.Error(t, err)
+}
+
+func TestParsePartial(t *testing.T) {
+	input := "--- user\nquestion\n--- assistant (partial)\nthe begin"
+	blocks, err := parse.ParseBlocks(strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Equal(t, []parse.Block{
+		{Role: "user", Content: "question\n", Offset: 0},
+		{Role: "assistant", Content: "the begin\n", Partial: true, Offset: 18},
+	}, blocks)
+	assert.Equal(t, 1, parse.LastTurn(blocks))
+
+	_, err = parse.Messages(blocks)
+	var partial *parse.PartialReplyError
+	assert.ErrorAs(t, err, &partial)
+
+	// a reply interrupted before its first token is kept so that it can be
+	// resumed
+	blocks, err = parse.ParseBlocks(strings.NewReader("--- user\nquestion\n--- assistant (partial)\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []parse.Block{
+		{Role: "user", Content: "question\n", Offset: 0},
+		{Role: "assistant", Partial: true, Offset: 18},
+	}, blocks)
+
+	_, err = parse.ParseBlocks(strings.NewReader("--- user (partial)\nquestion\n"))
+	var invalid *parse.InvalidPartialError
+	assert.ErrorAs(t, err, &invalid)
+}
@@ -0,0 +1,52 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pick resolves the last group of alternative assistant replies in the chat
+// file input. Alternative n (1-based) is kept as a regular assistant reply and
+// the other alternatives are dropped. The rest of the file is left as is.
+func Pick(input string, n int) (string, error) {
+	blocks, err := ParseBlocks(strings.NewReader(input))
+	if err != nil {
+		return "", err
+	}
+
+	end := len(blocks) - 1
+	for end >= 0 && blocks[end].Alternative == 0 {
+		end--
+	}
+	if end < 0 {
+		return "", fmt.Errorf("chat file has no alternative replies to pick from")
+	}
+	start := end
+	for start > 0 && blocks[start-1].Alternative != 0 {
+		start--
+	}
+
+	var picked *Block
+	for i := start; i <= end; i++ {
+		if blocks[i].Alternative == n {
+			picked = &blocks[i]
+			break
+		}
+	}
+	if picked == nil {
+		return "", fmt.Errorf("alternative %d does not exist, there are %d alternatives", n, end-start+1)
+	}
+
+	rest := len(input)
+	if end+1 < len(blocks) {
+		rest = blocks[end+1].Offset
+	}
+
+	var sb strings.Builder
+	sb.WriteString(input[:blocks[start].Offset])
+	sb.WriteString(FormatBoundary(picked.Role, 1, 1))
+	sb.WriteRune('\n')
+	sb.WriteString(picked.Content)
+	sb.WriteString(input[rest:])
+	return sb.String(), nil
+}
@@ -0,0 +1,167 @@
+package profile
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/secret"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI api version used when a profile
+// does not set one.
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// AzureConfig configures a provider of type azure. Azure OpenAI serves every
+// model from a deployment of its own, authenticated by the provider's api key
+// or by an Entra ID token.
+type AzureConfig struct {
+	APIVersion string `json:"api_version,omitempty"`
+	// Deployments maps models to the names of their deployments. A model
+	// without one is sent to the deployment named after it.
+	Deployments map[string]string `json:"deployments,omitempty"`
+	// TokenCommand prints an Entra ID access token, which is used instead of
+	// the api key, such as "az account get-access-token --resource
+	// https://cognitiveservices.azure.com --query accessToken -o tsv".
+	TokenCommand string `json:"token_command,omitempty"`
+}
+
+// Deployment returns the name of the deployment serving model.
+func (a *AzureConfig) Deployment(model string) string {
+	if a != nil {
+		if deployment, ok := a.Deployments[model]; ok {
+			return deployment
+		}
+	}
+	return model
+}
+
+// azureTokenTTL is how long an Entra ID token is reused before the token
+// command runs again. The tokens are valid for about an hour.
+var azureTokenTTL = 5 * time.Minute
+
+// azureStreamer builds the streamer for an Azure OpenAI resource. The api key
+// is read once and an Entra ID token whenever the cached one is too old, the
+// client of each deployment when it is first used.
+func (c *Profile) azureStreamer(endpoint string, azure *AzureConfig, apiKey func() (string, error)) (chat.Streamer, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("the azure provider needs an endpoint, such as https://NAME.openai.azure.com")
+	}
+	if azure == nil {
+		azure = &AzureConfig{}
+	}
+	version := azure.APIVersion
+	if version == "" {
+		version = DefaultAzureAPIVersion
+	}
+
+	// the client reads OPENAI_API_KEY into the authorization header by
+	// default, which must not be sent to another provider
+	auth := []option.RequestOption{
+		option.WithHeaderDel("authorization"),
+		option.WithQuery("api-version", version),
+	}
+	var credential string
+	if azure.TokenCommand != "" {
+		// the first token is read right away so that a failing command is
+		// reported before any request is sent
+		tokens := &azureToken{command: azure.TokenCommand}
+		token, err := tokens.get(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		credential = token
+		auth = append(auth, option.WithMiddleware(tokens.middleware))
+	} else {
+		key, err := apiKey()
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			return nil, fmt.Errorf("the azure provider needs an api key or azure.token_command")
+		}
+		credential = key
+		auth = append(auth, option.WithHeader("api-key", key))
+	}
+
+	opts, err := c.clientOptions(auth, credential)
+	if err != nil {
+		return nil, err
+	}
+	return &azureDeployments{
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		config:    azure,
+		opts:      opts,
+		streamers: map[string]chat.Streamer{},
+	}, nil
+}
+
+// azureToken caches the output of an Entra ID token command for
+// azureTokenTTL.
+type azureToken struct {
+	command string
+
+	mu      sync.Mutex
+	token   string
+	fetched time.Time
+}
+
+func (a *azureToken) get(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Since(a.fetched) < azureTokenTTL {
+		return a.token, nil
+	}
+	token, err := secret.Command(ctx, a.command)
+	if err != nil {
+		return "", err
+	}
+	a.token, a.fetched = token, time.Now()
+	return token, nil
+}
+
+// middleware sets the authorization header of every request to a token that
+// has not expired.
+func (a *azureToken) middleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	token, err := a.get(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return next(req)
+}
+
+// azureDeployments sends each request to the deployment of its model.
+type azureDeployments struct {
+	endpoint string
+	config   *AzureConfig
+	opts     []option.RequestOption
+
+	mu        sync.Mutex
+	streamers map[string]chat.Streamer
+}
+
+func (a *azureDeployments) ChatStream(ctx context.Context, request chat.Input, onData func(message string) error) error {
+	return a.streamer(a.config.Deployment(request.Model)).ChatStream(ctx, request, onData)
+}
+
+func (a *azureDeployments) streamer(deployment string) chat.Streamer {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if streamer, ok := a.streamers[deployment]; ok {
+		return streamer
+	}
+
+	base := a.endpoint + "/openai/deployments/" + url.PathEscape(deployment) + "/"
+	opts := append([]option.RequestOption{option.WithBaseURL(base)}, a.opts...)
+	streamer := chat.NewOpenAIStreamer(openai.NewClient(opts...))
+	a.streamers[deployment] = streamer
+	return streamer
+}
@@ -0,0 +1,160 @@
+package profile
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/chat"
+)
+
+// azureServer stands in for an Azure OpenAI resource. It records the
+// requests it receives and streams "hello" back.
+type azureServer struct {
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func (s *azureServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, token := range []string{"hel", "lo"} {
+		fmt.Fprintf(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"created\":0,\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", token)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// askProfile asks model "hi" with the client of p.
+func askProfile(t *testing.T, p *Profile, model string) string {
+	t.Helper()
+	client, err := p.Client()
+	require.NoError(t, err)
+
+	var reply strings.Builder
+	err = client.ChatStream(context.Background(), chat.Input{
+		Model:    model,
+		Messages: []chat.Message{{Role: "user", Content: "hi"}},
+	}, func(message string) error {
+		reply.WriteString(message)
+		return nil
+	})
+	require.NoError(t, err)
+	return reply.String()
+}
+
+func TestAzure(t *testing.T) {
+	handler := &azureServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+
+	p := &Profile{
+		Provider:          ProviderAzure,
+		OpenAIAPIEndpoint: server.URL,
+		OpenAIAPIKey:      "azure-key",
+		Azure:             &AzureConfig{Deployments: map[string]string{"gpt-4o": "prod-4o"}},
+	}
+	assert.Equal(t, "hello", askProfile(t, p, "gpt-4o"))
+	assert.Equal(t, "hello", askProfile(t, p, "gpt-4.1"))
+
+	require.Len(t, handler.requests, 2)
+	first := handler.requests[0]
+	assert.Equal(t, "/openai/deployments/prod-4o/chat/completions", first.URL.Path)
+	assert.Equal(t, DefaultAzureAPIVersion, first.URL.Query().Get("api-version"))
+	assert.Equal(t, "azure-key", first.Header.Get("api-key"))
+	assert.Empty(t, first.Header.Get("Authorization"))
+	assert.Equal(t, "/openai/deployments/gpt-4.1/chat/completions", handler.requests[1].URL.Path)
+}
+
+func TestAzureToken(t *testing.T) {
+	handler := &azureServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	p := &Profile{
+		Providers: map[string]*ProviderConfig{"corp": {
+			Type:     ProviderAzure,
+			Endpoint: server.URL + "/",
+			Azure:    &AzureConfig{APIVersion: "2025-01-01-preview", TokenCommand: "echo entra-token"},
+		}},
+		Provider: ProviderFake,
+	}
+	assert.Equal(t, "hello", askProfile(t, p, "corp/gpt-4o"))
+
+	require.Len(t, handler.requests, 1)
+	request := handler.requests[0]
+	assert.Equal(t, "/openai/deployments/gpt-4o/chat/completions", request.URL.Path)
+	assert.Equal(t, "2025-01-01-preview", request.URL.Query().Get("api-version"))
+	assert.Equal(t, "Bearer entra-token", request.Header.Get("Authorization"))
+	assert.Empty(t, request.Header.Get("api-key"))
+}
+
+func TestAzureTokenRefresh(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the token command needs sh")
+	}
+	defer func(ttl time.Duration) { azureTokenTTL = ttl }(azureTokenTTL)
+	azureTokenTTL = 0
+
+	handler := &azureServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// the command prints a new token every time it runs
+	count := filepath.Join(t.TempDir(), "count")
+	p := &Profile{
+		Providers: map[string]*ProviderConfig{"corp": {
+			Type:     ProviderAzure,
+			Endpoint: server.URL,
+			Azure:    &AzureConfig{TokenCommand: "echo x >> " + count + " && wc -l < " + count},
+		}},
+		Provider: ProviderFake,
+	}
+	client, err := p.Client()
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		err := client.ChatStream(context.Background(), chat.Input{
+			Model:    "corp/gpt-4o",
+			Messages: []chat.Message{{Role: "user", Content: "hi"}},
+		}, func(string) error { return nil })
+		require.NoError(t, err)
+	}
+
+	// the first token was read when the client was built
+	require.Len(t, handler.requests, 2)
+	assert.Equal(t, "Bearer 2", handler.requests[0].Header.Get("Authorization"))
+	assert.Equal(t, "Bearer 3", handler.requests[1].Header.Get("Authorization"))
+}
+
+func TestAzureMissingCredentials(t *testing.T) {
+	_, err := (&Profile{Provider: ProviderAzure, OpenAIAPIEndpoint: "https://example.openai.azure.com"}).Client()
+	assert.ErrorContains(t, err, "api key")
+
+	_, err = (&Profile{Provider: ProviderAzure, OpenAIAPIKey: "key"}).Client()
+	assert.ErrorContains(t, err, "endpoint")
+}
+
+func TestAzureDeploymentsKey(t *testing.T) {
+	p := &Profile{}
+	require.NoError(t, p.Set("azure.deployments", "gpt-4.1=g41, gpt-4o=prod-4o"))
+	assert.Equal(t, map[string]string{"gpt-4.1": "g41", "gpt-4o": "prod-4o"}, p.Azure.Deployments)
+
+	setting, err := p.Get("azure.deployments")
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4.1=g41,gpt-4o=prod-4o", setting.Value)
+
+	var invalid *InvalidValueError
+	assert.ErrorAs(t, p.Set("azure.deployments", "gpt-4o"), &invalid)
+}
@@ -0,0 +1,243 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLoggedBodySize is the largest body with a known length that is logged
+// in one piece. Other bodies are logged chunk by chunk as they are read.
+const maxLoggedBodySize = 1024 * 16
+
+const redacted = "[REDACTED]"
+
+// secretHeaders are the headers whose values are never logged.
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Api-Key":             true,
+	"X-Api-Key":           true,
+	"X-Goog-Api-Key":      true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// secretRegexp matches strings that look like api keys.
+var secretRegexp = regexp.MustCompile(`\b(sk-[A-Za-z0-9_\-]{8,}|AIza[0-9A-Za-z_\-]{30,})`)
+
+// DebugLog writes debug events to a writer as text or as JSON lines.
+type DebugLog struct {
+	mu      sync.Mutex
+	writer  io.Writer
+	json    bool
+	secrets []string
+	closer  io.Closer
+}
+
+// debugEvent is a single entry of the debug log.
+type debugEvent struct {
+	Time    time.Time           `json:"time"`
+	Type    string              `json:"type"`
+	Method  string              `json:"method,omitempty"`
+	URL     string              `json:"url,omitempty"`
+	Status  string              `json:"status,omitempty"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    string              `json:"body,omitempty"`
+	Error   string              `json:"error,omitempty"`
+}
+
+// NewDebugLog creates a DebugLog that writes to writer. The format is either
+// "text" or "json".
+func NewDebugLog(writer io.Writer, format string) (*DebugLog, error) {
+	switch format {
+	case "", "text":
+		return &DebugLog{writer: writer}, nil
+	case "json":
+		return &DebugLog{writer: writer, json: true}, nil
+	default:
+		return nil, fmt.Errorf("invalid debug log format %q: expected text or json", format)
+	}
+}
+
+// OpenDebugLog creates a DebugLog that appends to the file at path.
+func OpenDebugLog(path string, format string) (*DebugLog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open debug log: %w", err)
+	}
+
+	log, err := NewDebugLog(file, format)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	log.closer = file
+	return log, nil
+}
+
+func (d *DebugLog) Close() error {
+	if d.closer == nil {
+		return nil
+	}
+	return d.closer.Close()
+}
+
+// addSecret makes sure secret is redacted wherever it appears in the log.
+// Providers add their secrets when they are first used, which may be while
+// others are already logging.
+func (d *DebugLog) addSecret(secret string) {
+	if strings.TrimSpace(secret) != "" {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.secrets = append(d.secrets, secret)
+	}
+}
+
+func (d *DebugLog) redact(value string) string {
+	d.mu.Lock()
+	secrets := d.secrets
+	d.mu.Unlock()
+	for _, secret := range secrets {
+		value = strings.ReplaceAll(value, secret, redacted)
+	}
+	return secretRegexp.ReplaceAllString(value, redacted)
+}
+
+func (d *DebugLog) redactHeaders(header http.Header) map[string][]string {
+	result := make(map[string][]string, len(header))
+	for name, values := range header {
+		redactedValues := make([]string, len(values))
+		for i, value := range values {
+			if secretHeaders[http.CanonicalHeaderKey(name)] {
+				redactedValues[i] = redacted
+			} else {
+				redactedValues[i] = d.redact(value)
+			}
+		}
+		result[name] = redactedValues
+	}
+	return result
+}
+
+func (d *DebugLog) log(event debugEvent) {
+	event.Time = time.Now()
+	event.URL = d.redact(event.URL)
+	event.Body = d.redact(event.Body)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.json {
+		json.NewEncoder(d.writer).Encode(event)
+		return
+	}
+
+	var sb strings.Builder
+	switch event.Type {
+	case "request":
+		fmt.Fprintf(&sb, "Request: %s %s\n", event.Method, event.URL)
+	case "response":
+		fmt.Fprintf(&sb, "Response: %s\n", event.Status)
+	case "chunk":
+		fmt.Fprintf(&sb, "Chunk: ")
+	case "error":
+		fmt.Fprintf(&sb, "Error: %s\n", event.Error)
+	}
+
+	names := make([]string, 0, len(event.Headers))
+	for name := range event.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&sb, "%s: %s\n", name, strings.Join(event.Headers[name], ", "))
+	}
+
+	if event.Body != "" {
+		sb.WriteString(event.Body)
+		if !strings.HasSuffix(event.Body, "\n") {
+			sb.WriteRune('\n')
+		}
+	}
+	if event.Type != "chunk" {
+		sb.WriteRune('\n')
+	}
+	io.WriteString(d.writer, sb.String())
+}
+
+// loggingRoundTripper writes every request and response to a DebugLog.
+// Secrets are redacted and streamed bodies are logged chunk by chunk.
+type loggingRoundTripper struct {
+	inner http.RoundTripper
+	log   *DebugLog
+}
+
+func (l loggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	event := debugEvent{
+		Type:    "request",
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Headers: l.log.redactHeaders(req.Header),
+	}
+	if req.Body != nil && req.ContentLength > 0 && req.ContentLength < maxLoggedBodySize {
+		buf, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		event.Body = string(buf)
+		req.Body = io.NopCloser(bytes.NewBuffer(buf))
+	}
+	l.log.log(event)
+
+	resp, err := l.inner.RoundTrip(req)
+	if err != nil {
+		l.log.log(debugEvent{Type: "error", Error: err.Error()})
+		return nil, err
+	}
+
+	event = debugEvent{
+		Type:    "response",
+		Status:  resp.Status,
+		Headers: l.log.redactHeaders(resp.Header),
+	}
+	if resp.ContentLength > 0 && resp.ContentLength < maxLoggedBodySize {
+		buf, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		event.Body = string(buf)
+		resp.Body = io.NopCloser(bytes.NewBuffer(buf))
+	} else if resp.Body != nil {
+		resp.Body = &loggingBody{inner: resp.Body, log: l.log}
+	}
+	l.log.log(event)
+
+	return resp, nil
+}
+
+// loggingBody logs a response body chunk by chunk as it is read.
+type loggingBody struct {
+	inner io.ReadCloser
+	log   *DebugLog
+}
+
+func (b *loggingBody) Read(p []byte) (int, error) {
+	n, err := b.inner.Read(p)
+	if n > 0 {
+		b.log.log(debugEvent{Type: "chunk", Body: string(p[:n])})
+	}
+	return n, err
+}
+
+func (b *loggingBody) Close() error {
+	return b.inner.Close()
+}
@@ -0,0 +1,88 @@
+package profile
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggingRoundTripperRedactsSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, "data: leaked sk-abcdefghijklmnop\n\n")
+		w.(http.Flusher).Flush()
+		io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	var out strings.Builder
+	log, err := NewDebugLog(&out, "json")
+	require.NoError(t, err)
+	log.addSecret("configured-secret")
+
+	client := &http.Client{Transport: loggingRoundTripper{inner: http.DefaultTransport, log: log}}
+	req, err := http.NewRequest("POST", server.URL+"?key=configured-secret", strings.NewReader(`{"key":"configured-secret"}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer sk-abcdefghijklmnop")
+	req.Header.Set("X-Team", "infra")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// the caller still receives the unredacted body
+	assert.Contains(t, string(body), "sk-abcdefghijklmnop")
+
+	logged := out.String()
+	assert.NotContains(t, logged, "sk-abcdefghijklmnop")
+	assert.NotContains(t, logged, "configured-secret")
+
+	var types []string
+	for _, line := range strings.Split(strings.TrimSpace(logged), "\n") {
+		var event debugEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		types = append(types, event.Type)
+		if event.Type == "request" {
+			assert.Equal(t, []string{redacted}, event.Headers["Authorization"])
+			assert.Equal(t, []string{"infra"}, event.Headers["X-Team"])
+		}
+	}
+	assert.Equal(t, "request", types[0])
+	assert.Equal(t, "response", types[1])
+	assert.Contains(t, types[2:], "chunk")
+}
+
+func TestDebugLogAddSecretWhileLogging(t *testing.T) {
+	var out strings.Builder
+	log, err := NewDebugLog(&out, "text")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			log.log(debugEvent{Type: "request", Method: "POST", URL: "https://example.com", Body: "secret-b"})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		log.addSecret("secret-a")
+		log.addSecret("secret-b")
+	}()
+	wg.Wait()
+
+	log.log(debugEvent{Type: "request", Method: "POST", URL: "https://example.com", Body: "secret-a secret-b"})
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.NotContains(t, lines[len(lines)-1], "secret-")
+}
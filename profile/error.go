@@ -0,0 +1,50 @@
+package profile
+
+import "fmt"
+
+// UnknownKeyError is returned for a setting that is not in Keys.
+type UnknownKeyError struct {
+	Name string
+}
+
+func (e *UnknownKeyError) Error() string {
+	return fmt.Sprintf("unknown config key %q", e.Name)
+}
+
+// InvalidValueError is returned when a setting is set to an invalid value.
+type InvalidValueError struct {
+	Key   string
+	Value string
+	Err   error
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("invalid value %q for %s: %v", e.Value, e.Key, e.Err)
+}
+
+func (e *InvalidValueError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidNameError is returned for a profile name that cannot be used.
+type InvalidNameError struct {
+	Name string
+}
+
+func (e *InvalidNameError) Error() string {
+	return fmt.Sprintf("invalid profile name %q: only letters, digits, '.', '-' and '_' are allowed", e.Name)
+}
+
+// NotFoundError is returned when a profile does not exist.
+type NotFoundError struct {
+	Name string
+}
+
+func (e *NotFoundError) Error() string { return fmt.Sprintf("profile not found: %s", e.Name) }
+
+// ExistsError is returned when creating a profile that already exists.
+type ExistsError struct {
+	Name string
+}
+
+func (e *ExistsError) Error() string { return fmt.Sprintf("profile already exists: %s", e.Name) }
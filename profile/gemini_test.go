@@ -0,0 +1,65 @@
+package profile
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// geminiServer stands in for the Gemini api. It records the api keys of the
+// requests it receives and answers "hello".
+type geminiServer struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (s *geminiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.keys = append(s.keys, r.Header.Get("X-Goog-Api-Key"))
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"hello\"}]},\"finishReason\":\"STOP\"}]}\n\n")
+}
+
+func TestGeminiKey(t *testing.T) {
+	handler := &geminiServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	stored := &Profile{Provider: ProviderGemini, OpenAIAPIEndpoint: server.URL, OpenAIAPIKey: "sk-stored"}
+	assert.Equal(t, DefaultGeminiModel, stored.Model())
+
+	// the OpenAI key is never sent to Google
+	useEnv(t, map[string]string{"OPENAI_API_KEY": "sk-openai"})
+	p, err := stored.Resolve()
+	require.NoError(t, err)
+	_, err = p.Client()
+	assert.EqualError(t, err, "the gemini provider needs an api key")
+
+	useEnv(t, map[string]string{"OPENAI_API_KEY": "sk-openai", "GEMINI_API_KEY": "gemini-env"})
+	p, err = stored.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", askProfile(t, p, p.Model()))
+
+	stored.GeminiAPIKey = "gemini-stored"
+	useEnv(t, map[string]string{})
+	p, err = stored.Resolve()
+	require.NoError(t, err)
+	askProfile(t, p, p.Model())
+
+	assert.Equal(t, []string{"gemini-env", "gemini-stored"}, handler.keys)
+
+	settings, err := stored.Explain()
+	require.NoError(t, err)
+	for _, setting := range settings {
+		if setting.Name == "model" {
+			assert.Equal(t, DefaultGeminiModel, setting.Value)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package profile
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/secret"
+)
+
+// the types of a Key
+const (
+	TypeString   = "string"
+	TypeURL      = "url"
+	TypeEnum     = "enum"
+	TypeDuration = "duration"
+	TypeBool     = "bool"
+	TypeInt      = "int"
+	TypeList     = "list"
+	// TypeMap is a list of name=value pairs.
+	TypeMap = "map"
+)
+
+// Key describes a setting of a profile.
+type Key struct {
+	// Name is the dotted path of the setting in the profile. A "*" segment
+	// stands for any name, as in "providers.*.endpoint".
+	Name string
+	// Aliases are other names the key can be set by.
+	Aliases []string
+	Type    string
+	// Values are the allowed values of an enum.
+	Values []string
+	Help   string
+	// Env is the environment variable that overrides the profile's value.
+	Env string
+	// Default is the value used when the setting is not set.
+	Default string
+	// Secret keys are masked when printed and kept in the profile's secret
+	// store if it has one.
+	Secret bool
+
+	// validate checks a value of the key, or every item of a list.
+	validate func(value string) error
+}
+
+// Keys is the registry of all the settings of a profile.
+var Keys = []*Key{
+	{Name: "model", Type: TypeString, Env: "HLP_MODEL", Default: DefaultModel,
+		Help: "the model requests use unless they name another one, " + DefaultGeminiModel + " by default with the gemini provider"},
+	{Name: "provider", Type: TypeEnum, Values: []string{ProviderOpenAI, ProviderAzure, ProviderGemini, ProviderFake}, Env: "HLP_PROVIDER", Default: ProviderOpenAI,
+		Help: "the provider answering requests"},
+	{Name: "openai_api_key", Type: TypeString, Env: "OPENAI_API_KEY", Secret: true,
+		Help: "the api key of the openai and azure providers"},
+	{Name: "gemini_api_key", Type: TypeString, Env: "GEMINI_API_KEY", Secret: true,
+		Help: "the api key of the gemini provider"},
+	{Name: "endpoint", Aliases: []string{"openai_api_endpoint"}, Type: TypeURL, Env: "OPENAI_API_ENDPOINT",
+		Help: "the base url of the provider's api"},
+	{Name: "api_key_command", Type: TypeString,
+		Help: "a command printing the api key, used instead of openai_api_key or gemini_api_key"},
+	{Name: "secret_backend", Type: TypeEnum, Values: []string{"profile", secret.BackendKeyring, secret.BackendFile}, Default: "profile",
+		Help: "where api keys are stored"},
+	{Name: "timeout", Type: TypeDuration, Env: "HLP_TIMEOUT", Default: DefaultTimeout.String(),
+		Help: "the total timeout of a request, 0s disables it"},
+	{Name: "idle_timeout", Type: TypeDuration, Env: "HLP_IDLE_TIMEOUT", Default: "0s",
+		Help: "cancel a request if no token arrives for this long, 0s disables it"},
+	{Name: "proxy", Type: TypeString, Env: "HLP_PROXY",
+		Help: "the HTTP(S) proxy requests go through", validate: validateProxy},
+	{Name: "ca_certs", Type: TypeList,
+		Help: "additional CA certificates in PEM format", validate: validateFile},
+	{Name: "headers.*", Type: TypeString,
+		Help: "an additional HTTP header"},
+	{Name: "middleware", Type: TypeList,
+		Help: "the registered middleware wrapped around requests", validate: validateMiddleware},
+	{Name: "prices", Type: TypeMap,
+		Help: "the prices of models as model=input/output pairs in US dollars per million tokens", validate: validatePrices},
+	{Name: "cache.enabled", Type: TypeBool, Default: "false",
+		Help: "answer identical requests from the response cache"},
+	{Name: "cache.ttl", Type: TypeDuration, Default: "168h0m0s",
+		Help: "how long cached responses are kept"},
+	{Name: "cache.max_size", Type: TypeInt, Default: "67108864",
+		Help: "the size of the response cache in bytes"},
+	{Name: "fake.responses", Type: TypeList,
+		Help: "the replies of the fake provider, with commas inside a reply escaped as \\,"},
+	{Name: "fake.delay", Type: TypeDuration, Default: "0s",
+		Help: "the delay between the tokens of the fake provider"},
+	{Name: "fake.failure", Type: TypeString,
+		Help: "the error the fake provider injects", validate: validateFakeFailure},
+	{Name: "azure.api_version", Type: TypeString, Env: "OPENAI_API_VERSION", Default: DefaultAzureAPIVersion,
+		Help: "the api-version of the azure provider"},
+	{Name: "azure.deployments", Type: TypeMap,
+		Help: "the deployments of the azure provider by model, as model=deployment pairs"},
+	{Name: "azure.token_command", Type: TypeString,
+		Help: "a command printing an Entra ID token for the azure provider, used instead of the api key"},
+	{Name: "providers.*.type", Type: TypeEnum, Values: []string{ProviderOpenAI, ProviderAzure, ProviderGemini, ProviderFake}, Default: ProviderOpenAI,
+		Help: "the type of an additional provider, used by models prefixed with its name"},
+	{Name: "providers.*.endpoint", Type: TypeURL,
+		Help: "the base url of an additional provider"},
+	{Name: "providers.*.api_key", Type: TypeString, Secret: true,
+		Help: "the api key of an additional provider"},
+	{Name: "providers.*.api_key_command", Type: TypeString,
+		Help: "a command printing the api key of an additional provider"},
+	{Name: "providers.*.azure.api_version", Type: TypeString, Default: DefaultAzureAPIVersion,
+		Help: "the api-version of an additional azure provider"},
+	{Name: "providers.*.azure.deployments", Type: TypeMap,
+		Help: "the deployments of an additional azure provider by model, as model=deployment pairs"},
+	{Name: "providers.*.azure.token_command", Type: TypeString,
+		Help: "a command printing an Entra ID token for an additional azure provider"},
+}
+
+// LookupKey returns the key matching name, such as "providers.local.endpoint".
+func LookupKey(name string) (*Key, error) {
+	for _, key := range Keys {
+		if key.Match(name) {
+			return key, nil
+		}
+	}
+	return nil, &UnknownKeyError{Name: name}
+}
+
+// Match reports whether name is the key or one of its aliases.
+func (k *Key) Match(name string) bool {
+	if matchPath(k.Name, name) {
+		return true
+	}
+	for _, alias := range k.Aliases {
+		if alias == name {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPath(pattern, name string) bool {
+	patternParts := strings.Split(pattern, ".")
+	nameParts := strings.Split(name, ".")
+	if len(patternParts) != len(nameParts) {
+		return false
+	}
+	for i, part := range patternParts {
+		if nameParts[i] == "" || (part != "*" && part != nameParts[i]) {
+			return false
+		}
+		if part == "*" && strings.Contains(nameParts[i], "/") {
+			// the names of providers are model prefixes
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that value is valid for the key.
+func (k *Key) Validate(value string) error {
+	if k.Type == TypeList {
+		return k.ValidateList(splitList(value))
+	}
+	err := k.validateType(value)
+	if err == nil && k.validate != nil {
+		err = k.validate(value)
+	}
+	if err != nil {
+		return &InvalidValueError{Key: k.Name, Value: value, Err: err}
+	}
+	return nil
+}
+
+// ValidateList checks that items are valid for a list key.
+func (k *Key) ValidateList(items []string) error {
+	if k.validate == nil {
+		return nil
+	}
+	for _, item := range items {
+		if err := k.validate(item); err != nil {
+			return &InvalidValueError{Key: k.Name, Value: item, Err: err}
+		}
+	}
+	return nil
+}
+
+func (k *Key) validateType(value string) error {
+	switch k.Type {
+	case TypeURL:
+		return validateURL(value, "http", "https")
+	case TypeEnum:
+		for _, allowed := range k.Values {
+			if value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("expected one of %s", strings.Join(k.Values, ", "))
+	case TypeDuration:
+		_, err := ParseDuration(value)
+		return err
+	case TypeBool:
+		_, err := strconv.ParseBool(value)
+		return err
+	case TypeMap:
+		_, err := splitMap(value)
+		return err
+	case TypeInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err == nil && n < 0 {
+			err = fmt.Errorf("cannot be negative")
+		}
+		return err
+	}
+	return nil
+}
+
+func validateURL(value string, schemes ...string) error {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("missing host")
+	}
+	for _, scheme := range schemes {
+		if parsed.Scheme == scheme {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected a %s url", strings.Join(schemes, " or "))
+}
+
+func validateProxy(value string) error {
+	return validateURL(value, "http", "https", "socks5")
+}
+
+func validateFile(path string) error {
+	_, err := os.Stat(path)
+	return err
+}
+
+func validateMiddleware(name string) error {
+	_, err := chat.LookupMiddleware(name)
+	return err
+}
+
+func validateFakeFailure(value string) error {
+	return chat.ValidateFakeFailure(chat.FakeFailure(value))
+}
+
+// splitList splits a comma separated list. An item can contain a comma
+// escaped as "\,".
+func splitList(value string) []string {
+	var list []string
+	var item strings.Builder
+	add := func() {
+		if trimmed := strings.TrimSpace(item.String()); trimmed != "" {
+			list = append(list, trimmed)
+		}
+		item.Reset()
+	}
+	for i := 0; i < len(value); i++ {
+		switch {
+		case strings.HasPrefix(value[i:], `\,`):
+			item.WriteByte(',')
+			i++
+		case value[i] == ',':
+			add()
+		default:
+			item.WriteByte(value[i])
+		}
+	}
+	add()
+	return list
+}
+
+// joinList joins items into a list that splitList splits into them again.
+func joinList(items []string) string {
+	escaped := make([]string, len(items))
+	for i, item := range items {
+		escaped[i] = strings.ReplaceAll(item, ",", `\,`)
+	}
+	return strings.Join(escaped, ",")
+}
+
+// splitMap splits a comma separated list of name=value pairs.
+func splitMap(value string) (map[string]string, error) {
+	pairs := map[string]string{}
+	for _, item := range splitList(value) {
+		name, value, ok := strings.Cut(item, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("expected name=value, got %q", item)
+		}
+		pairs[name] = value
+	}
+	return pairs, nil
+}
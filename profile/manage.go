@@ -0,0 +1,220 @@
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// defaultFile stores the name of the profile used when none is given.
+const defaultFile = "default_profile"
+
+var nameRegexp = regexp.MustCompile(`^[A-Za-z0-9_\-][A-Za-z0-9_.\-]*$`)
+
+// reserved are the files in the config directory that are not profiles.
+var reserved = map[string]bool{
+	defaultFile:   true,
+	"secrets.enc": true,
+	"secrets.key": true,
+}
+
+// ValidateName checks that name can be used as a profile name.
+func ValidateName(name string) error {
+	if !nameRegexp.MatchString(name) || reserved[name] {
+		return &InvalidNameError{Name: name}
+	}
+	return nil
+}
+
+// Default returns the name of the profile used when none is given: the one
+// set with Use, or DefaultName.
+func Default() string {
+	buf, err := os.ReadFile(filepath.Join(Dir(), defaultFile))
+	if err != nil {
+		return DefaultName
+	}
+	if name := strings.TrimSpace(string(buf)); name != "" {
+		return name
+	}
+	return DefaultName
+}
+
+// Use makes the existing profile name the default profile.
+func Use(name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if name != DefaultName && !Exists(name) {
+		return &NotFoundError{Name: name}
+	}
+	if name == DefaultName {
+		err := os.Remove(filepath.Join(Dir(), defaultFile))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return writeFile(filepath.Join(Dir(), defaultFile), []byte(name+"\n"), 0644)
+}
+
+// Exists reports whether the profile name is stored in the config directory.
+func Exists(name string) bool {
+	if ValidateName(name) != nil {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(Dir(), name))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// Names returns the names of the profiles in the config directory, in
+// sorted order. Files that are not JSON objects are skipped.
+func Names() ([]string, error) {
+	entries, err := os.ReadDir(Dir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || ValidateName(entry.Name()) != nil {
+			continue
+		}
+		buf, err := os.ReadFile(filepath.Join(Dir(), entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var object map[string]json.RawMessage
+		if json.Unmarshal(buf, &object) != nil {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Create stores a new, empty profile called name.
+func Create(name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if Exists(name) {
+		return &ExistsError{Name: name}
+	}
+	return (&Profile{name: name}).Write()
+}
+
+// Copy stores a copy of the profile src, including its secrets, as dst.
+func Copy(src, dst string) error {
+	if err := ValidateName(dst); err != nil {
+		return err
+	}
+	if !Exists(src) {
+		return &NotFoundError{Name: src}
+	}
+	if Exists(dst) {
+		return &ExistsError{Name: dst}
+	}
+
+	p, err := Read(src)
+	if err != nil {
+		return err
+	}
+
+	fields := p.secretFields()
+	values := map[string]string{}
+	for key, field := range fields {
+		value, err := p.secretValue(key, *field)
+		if err != nil {
+			return err
+		}
+		values[key] = value
+	}
+
+	p.name = dst
+	for key, field := range fields {
+		if err := p.setSecretValue(key, values[key], field); err != nil {
+			return err
+		}
+	}
+	return p.Write()
+}
+
+// Remove deletes the profile name and its secrets. If it is the default
+// profile, DefaultName becomes the default again.
+func Remove(name string) error {
+	if !Exists(name) {
+		return &NotFoundError{Name: name}
+	}
+
+	p, err := Read(name)
+	if err != nil {
+		return err
+	}
+	store, err := p.SecretStore()
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		for key := range p.secretFields() {
+			if err := store.Delete(p.secretName(key)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := os.Remove(filepath.Join(Dir(), name)); err != nil {
+		return err
+	}
+	if Default() == name {
+		return Use(DefaultName)
+	}
+	return nil
+}
+
+// Rename moves the profile src, including its secrets, to dst.
+func Rename(src, dst string) error {
+	if err := Copy(src, dst); err != nil {
+		return err
+	}
+	wasDefault := Default() == src
+	if err := Remove(src); err != nil {
+		return err
+	}
+	if wasDefault {
+		return Use(dst)
+	}
+	return nil
+}
+
+// writeFile atomically replaces the file at path with data.
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
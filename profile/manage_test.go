@@ -0,0 +1,97 @@
+package profile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/secret"
+)
+
+// useTempDir points the config directory to a temporary directory for the
+// duration of the test.
+func useTempDir(t *testing.T) string {
+	t.Helper()
+	tmp := t.TempDir()
+	old := dir
+	dir = func() string { return tmp }
+	t.Cleanup(func() { dir = old })
+	return tmp
+}
+
+func TestProfiles(t *testing.T) {
+	tmp := useTempDir(t)
+
+	require.NoError(t, Create("work"))
+	var exists *ExistsError
+	assert.True(t, errors.As(Create("work"), &exists))
+
+	p, err := Read("work")
+	require.NoError(t, err)
+	require.NoError(t, p.Set("model", "gpt-4o"))
+	require.NoError(t, p.Set("secret_backend", "file"))
+	require.NoError(t, p.Set("openai_api_key", "sk-work"))
+	require.NoError(t, p.Write())
+
+	require.NoError(t, Copy("work", "personal"))
+	copied, err := Read("personal")
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o", copied.DefaultModel)
+	key, err := copied.APIKey()
+	require.NoError(t, err)
+	assert.Equal(t, "sk-work", key)
+
+	// files that are not profiles are not listed
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, "notes.txt"), []byte("hello"), 0644))
+	names, err := Names()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"personal", "work"}, names)
+
+	require.NoError(t, Remove("personal"))
+	assert.False(t, Exists("personal"))
+	var notFound *NotFoundError
+	assert.True(t, errors.As(Remove("personal"), &notFound))
+
+	// the secret of the removed profile is gone, the other one is kept
+	p, err = Read("work")
+	require.NoError(t, err)
+	key, err = p.APIKey()
+	require.NoError(t, err)
+	assert.Equal(t, "sk-work", key)
+	_, err = secret.NewFile(tmp).Get("personal/openai_api_key")
+	assert.Error(t, err)
+}
+
+func TestUse(t *testing.T) {
+	useTempDir(t)
+
+	assert.Equal(t, DefaultName, Default())
+	var notFound *NotFoundError
+	assert.True(t, errors.As(Use("work"), &notFound))
+
+	require.NoError(t, Create("work"))
+	require.NoError(t, Use("work"))
+	assert.Equal(t, "work", Default())
+
+	p, err := Read("")
+	require.NoError(t, err)
+	assert.Equal(t, "work", p.Name())
+
+	require.NoError(t, Rename("work", "job"))
+	assert.Equal(t, "job", Default())
+
+	require.NoError(t, Remove("job"))
+	assert.Equal(t, DefaultName, Default())
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"work", "work.json", "a-b_c"} {
+		assert.NoError(t, ValidateName(name), name)
+	}
+	for _, name := range []string{"", ".hidden", "../x", "a/b", "secrets.key", defaultFile} {
+		assert.Error(t, ValidateName(name), name)
+	}
+}
@@ -0,0 +1,87 @@
+package profile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yiblet/hlp/chat"
+)
+
+// Price is the price of a model in US dollars per million tokens.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// Cost returns the cost of usage in US dollars.
+func (p Price) Cost(usage chat.Usage) float64 {
+	return (float64(usage.InputTokens)*p.Input + float64(usage.OutputTokens)*p.Output) / 1e6
+}
+
+// DefaultPrices are the list prices of common models as "input/output" in
+// US dollars per million tokens. They go out of date, so the prices of a
+// profile take precedence.
+var DefaultPrices = map[string]string{
+	"gpt-4o":           "2.50/10.00",
+	"gpt-4o-mini":      "0.15/0.60",
+	"gpt-4.1":          "2.00/8.00",
+	"gpt-4.1-mini":     "0.40/1.60",
+	"gpt-4.1-nano":     "0.10/0.40",
+	"o3-mini":          "1.10/4.40",
+	"gemini-1.5-flash": "0.075/0.30",
+	"gemini-1.5-pro":   "1.25/5.00",
+}
+
+// ParsePrice parses a price such as "2.50/10.00", the input and output
+// prices per million tokens.
+func ParsePrice(value string) (Price, error) {
+	input, output, ok := strings.Cut(value, "/")
+	if !ok {
+		return Price{}, fmt.Errorf("expected input/output prices per million tokens, such as 2.50/10.00")
+	}
+	var price Price
+	var err error
+	if price.Input, err = strconv.ParseFloat(strings.TrimSpace(input), 64); err != nil {
+		return Price{}, err
+	}
+	if price.Output, err = strconv.ParseFloat(strings.TrimSpace(output), 64); err != nil {
+		return Price{}, err
+	}
+	if price.Input < 0 || price.Output < 0 {
+		return Price{}, fmt.Errorf("prices cannot be negative")
+	}
+	return price, nil
+}
+
+// Price returns the price of model from the profile's prices or
+// DefaultPrices. A model prefixed with the name of a provider, such as
+// "local/llama3", is also looked up without the prefix.
+func (c *Profile) Price(model string) (Price, bool) {
+	names := []string{model}
+	if _, name, ok := strings.Cut(model, "/"); ok {
+		names = append(names, name)
+	}
+	for _, prices := range []map[string]string{c.Prices, DefaultPrices} {
+		for _, name := range names {
+			if value, ok := prices[name]; ok {
+				price, err := ParsePrice(value)
+				return price, err == nil
+			}
+		}
+	}
+	return Price{}, false
+}
+
+func validatePrices(value string) error {
+	prices, err := splitMap(value)
+	if err != nil {
+		return err
+	}
+	for model, price := range prices {
+		if _, err := ParsePrice(price); err != nil {
+			return fmt.Errorf("price of %s: %w", model, err)
+		}
+	}
+	return nil
+}
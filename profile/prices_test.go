@@ -0,0 +1,31 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/chat"
+)
+
+func TestPrice(t *testing.T) {
+	p := &Profile{}
+	require.NoError(t, p.Set("prices", "gpt-4o=1/2,llama3=0/0"))
+
+	price, ok := p.Price("gpt-4o")
+	require.True(t, ok)
+	assert.Equal(t, Price{Input: 1, Output: 2}, price)
+	assert.InDelta(t, 0.005, price.Cost(chat.Usage{InputTokens: 1000, OutputTokens: 2000}), 1e-9)
+
+	price, ok = p.Price("local/llama3")
+	require.True(t, ok)
+	assert.Equal(t, Price{}, price)
+
+	_, ok = p.Price("gpt-4o-mini")
+	assert.True(t, ok, "falls back to the default prices")
+	_, ok = p.Price("unknown")
+	assert.False(t, ok)
+
+	var invalid *InvalidValueError
+	assert.ErrorAs(t, p.Set("prices", "gpt-4o=cheap"), &invalid)
+}
@@ -0,0 +1,380 @@
+// Package profile loads and stores hlp's configuration profiles and builds
+// the chat.Streamer a profile describes. A profile is a JSON file in the
+// user's config directory, selected by name with --config or HLP_CONFIG.
+package profile
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kirsle/configdir"
+	"github.com/yiblet/hlp/cache"
+	"github.com/yiblet/hlp/cassette"
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/project"
+	"github.com/yiblet/hlp/prompt"
+)
+
+// DefaultName is the name of the profile used when none is given.
+const DefaultName = "configuration.json"
+
+// DefaultModel is the model used when a profile does not set one.
+const DefaultModel = "gpt-4o-mini"
+
+// DefaultGeminiModel is the model used when a profile with the gemini
+// provider does not set one.
+const DefaultGeminiModel = "gemini-2.0-flash"
+
+// the providers a profile can use
+const (
+	ProviderOpenAI = "openai"
+	ProviderAzure  = "azure"
+	ProviderGemini = "gemini"
+	ProviderFake   = "fake"
+)
+
+// DefaultTimeout is the total timeout of a request if none is configured.
+const DefaultTimeout = time.Minute * 2
+
+// Profile is a named configuration. The exported fields without a json name
+// are runtime options that are never stored.
+type Profile struct {
+	OpenAIAPIKey      string                     `json:"openai_api_key,omitempty"`
+	GeminiAPIKey      string                     `json:"gemini_api_key,omitempty"`
+	APIKeyCommand     string                     `json:"api_key_command,omitempty"`
+	SecretBackend     string                     `json:"secret_backend,omitempty"`
+	OpenAIAPIEndpoint string                     `json:"endpoint,omitempty"`
+	DefaultModel      string                     `json:"model,omitempty"`
+	Timeout           *Duration                  `json:"timeout,omitempty"`
+	IdleTimeout       Duration                   `json:"idle_timeout,omitempty"`
+	Proxy             string                     `json:"proxy,omitempty"`
+	CACerts           []string                   `json:"ca_certs,omitempty"`
+	Headers           map[string]string          `json:"headers,omitempty"`
+	Provider          string                     `json:"provider,omitempty"`
+	Fake              *FakeConfig                `json:"fake,omitempty"`
+	Cache             *CacheConfig               `json:"cache,omitempty"`
+	Middleware        []string                   `json:"middleware,omitempty"`
+	Providers         map[string]*ProviderConfig `json:"providers,omitempty"`
+	Azure             *AzureConfig               `json:"azure,omitempty"`
+	Prices            map[string]string          `json:"prices,omitempty"`
+
+	// Debug logs every HTTP exchange to DebugLog, or to stderr if it is nil.
+	Debug    bool      `json:"-"`
+	DebugLog *DebugLog `json:"-"`
+	// RecordDir records every HTTP exchange into a cassette directory.
+	RecordDir string `json:"-"`
+	// ReplayDir answers requests from a cassette directory instead of the
+	// network.
+	ReplayDir string `json:"-"`
+	// Flags are settings passed on the command line by key name, such as
+	// "timeout". They take precedence over every other source, see Explain.
+	Flags map[string]string `json:"-"`
+	// ListFlags are list settings passed on the command line one item at a
+	// time, such as "ca_certs". Unlike in Flags their items are never split
+	// at commas.
+	ListFlags map[string][]string `json:"-"`
+	// Project holds the settings of the project in the working directory,
+	// which take precedence over the profile.
+	Project *project.Config `json:"-"`
+
+	name string
+	// sources are the sources of the settings of a resolved profile.
+	sources map[string]string
+}
+
+// FakeConfig configures the fake provider, which answers without network
+// access. See chat.FakeStreamer.
+type FakeConfig struct {
+	Responses []string         `json:"responses,omitempty"`
+	Delay     Duration         `json:"delay,omitempty"`
+	Failure   chat.FakeFailure `json:"failure,omitempty"`
+}
+
+// CacheConfig configures the response cache. See cache.Store.
+type CacheConfig struct {
+	Enabled bool     `json:"enabled,omitempty"`
+	TTL     Duration `json:"ttl,omitempty"`
+	MaxSize int64    `json:"max_size,omitempty"`
+}
+
+// Duration is a time.Duration that is stored as a string such as "2m30s" in
+// the profile.
+type Duration time.Duration
+
+// ParseDuration parses a non-negative duration such as "2m30s".
+func ParseDuration(value string) (Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("duration cannot be negative: %s", value)
+	}
+	return Duration(d), nil
+}
+
+func (d Duration) String() string { return time.Duration(d).String() }
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// RequestTimeout returns the total timeout of a request. Zero means there is
+// no timeout.
+func (c *Profile) RequestTimeout() time.Duration {
+	if c.Timeout == nil {
+		return DefaultTimeout
+	}
+	return time.Duration(*c.Timeout)
+}
+
+// Model returns the model requests use unless they name another one.
+func (c *Profile) Model() string {
+	if c.DefaultModel == "" {
+		return defaultModel(c.Provider)
+	}
+	return c.DefaultModel
+}
+
+// defaultModel returns the model of a profile with provider that does not
+// set one.
+func defaultModel(provider string) string {
+	if provider == ProviderGemini {
+		return DefaultGeminiModel
+	}
+	return DefaultModel
+}
+
+// transport builds the http transport with the configured proxy and
+// certificates. Exchanges are recorded to or replayed from a cassette
+// directory if one is set.
+func (c *Profile) transport() (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if c.Proxy != "" {
+		proxyURL, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if len(c.CACerts) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, path := range c.CACerts {
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read ca certificate: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", path)
+			}
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	var roundTripper http.RoundTripper = transport
+	if c.ReplayDir != "" {
+		replayer, err := cassette.NewReplayer(c.ReplayDir, true)
+		if err != nil {
+			return nil, err
+		}
+		roundTripper = replayer
+	}
+	if c.RecordDir != "" {
+		recorder, err := cassette.NewRecorder(c.RecordDir, roundTripper)
+		if err != nil {
+			return nil, err
+		}
+		roundTripper = recorder
+	}
+
+	return roundTripper, nil
+}
+
+// Client builds the Streamer of the profile's provider, wrapped in the
+// profile's middleware pipeline.
+func (c *Profile) Client() (chat.Streamer, error) {
+	apiKey := c.APIKey
+	if c.Provider == ProviderGemini {
+		// the OpenAI key is never sent to Google
+		apiKey = c.geminiAPIKey
+	}
+	streamer, err := c.providerStreamer(c.Provider, c.OpenAIAPIEndpoint, c.Azure, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Providers) > 0 {
+		streamer = &router{profile: c, fallback: streamer, streamers: map[string]chat.Streamer{}}
+	}
+
+	middlewares, err := c.middlewares()
+	if err != nil {
+		return nil, err
+	}
+	return chat.Chain(streamer, middlewares...), nil
+}
+
+// middlewares returns the pipeline wrapped around the provider's Streamer,
+// outermost first: the middlewares registered with chat.RegisterMiddleware
+// and named in the profile, in the order they are listed, then the response
+// cache and finally the request timeouts.
+func (c *Profile) middlewares() ([]chat.Middleware, error) {
+	var middlewares []chat.Middleware
+	for _, name := range c.Middleware {
+		middleware, err := chat.LookupMiddleware(name)
+		if err != nil {
+			return nil, err
+		}
+		middlewares = append(middlewares, middleware)
+	}
+
+	if c.Cache != nil && c.Cache.Enabled {
+		middlewares = append(middlewares, cache.Middleware(c.CacheStore(), c.Provider+" "+c.OpenAIAPIEndpoint))
+	}
+
+	middlewares = append(middlewares, chat.Timeout(c.RequestTimeout(), time.Duration(c.IdleTimeout)))
+	return middlewares, nil
+}
+
+// CacheStore returns the response cache stored in the config directory.
+func (c *Profile) CacheStore() *cache.Store {
+	store := cache.NewStore(filepath.Join(Dir(), "cache"), 0, 0)
+	if c.Cache != nil {
+		store.TTL = time.Duration(c.Cache.TTL)
+		store.MaxSize = c.Cache.MaxSize
+	}
+	return store
+}
+
+// Close releases the resources held by the profile.
+func (c *Profile) Close() error {
+	if c.DebugLog != nil {
+		return c.DebugLog.Close()
+	}
+	return nil
+}
+
+// Prompts returns the prompt library stored in the config directory,
+// including the prompts of the project.
+func (c *Profile) Prompts() *prompt.Library {
+	library := prompt.NewLibrary(filepath.Join(Dir(), "prompts"))
+	if c.Project != nil {
+		library.Project = c.Project.Prompts
+	}
+	return library
+}
+
+// DefaultPrompt returns the name of the prompt used when none is given, or
+// an empty string if there is none.
+func (c *Profile) DefaultPrompt() string {
+	if c.Project == nil {
+		return ""
+	}
+	return c.Project.Prompt
+}
+
+// Name returns the file name of the profile.
+func (c *Profile) Name() string {
+	if c.name == "" {
+		return DefaultName
+	}
+	return c.name
+}
+
+// Dir returns the config directory that holds the profiles.
+func Dir() string {
+	return dir()
+}
+
+// dir is replaced in tests.
+var dir = func() string {
+	// A common use case is to get a private config folder for your app to
+	// place its settings files into, that are specific to the local user.
+	return configdir.LocalConfig("hlp")
+}
+
+// Write stores the profile in the config directory.
+func (c *Profile) Write() error {
+	fileName := c.Name()
+
+	configPath := Dir()
+	err := configdir.MakePath(configPath) // Ensure it exists.
+	if err != nil {
+		return fmt.Errorf("cannot read path: %w", err)
+	}
+
+	// The profile may hold an api key, so only the user can read it.
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(configPath, fileName), append(data, '\n'), 0600)
+}
+
+// Read loads the profile called name from the config directory. An empty
+// name is the default profile, see Default. A profile that does not exist
+// yet is empty.
+func Read(name string) (*Profile, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = Default()
+	}
+
+	c := &Profile{name: name}
+	// A common use case is to get a private config folder for your app to
+	// place its settings files into, that are specific to the local user.
+	configPath := Dir()
+	err := os.MkdirAll(configPath, 0755) // Ensure it exists.
+	if err != nil {
+		return nil, fmt.Errorf("cannot read path: %w", err)
+	}
+
+	// Deal with a JSON configuration file in that folder.
+	configFile := filepath.Join(configPath, name)
+	if _, err = os.Stat(configFile); err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+
+	// Load the existing file.
+	fh, err := os.Open(configFile)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	decoder := json.NewDecoder(fh)
+	if err := decoder.Decode(c); err != nil {
+		return nil, err
+	}
+
+	c.name = name
+	return c, nil
+}
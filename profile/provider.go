@@ -0,0 +1,201 @@
+package profile
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/secret"
+)
+
+// ProviderConfig is an additional provider of a profile. A request uses it
+// when its model is prefixed with the provider's name, as in "local/llama3".
+type ProviderConfig struct {
+	Type          string       `json:"type,omitempty"`
+	Endpoint      string       `json:"endpoint,omitempty"`
+	APIKey        string       `json:"api_key,omitempty"`
+	APIKeyCommand string       `json:"api_key_command,omitempty"`
+	Azure         *AzureConfig `json:"azure,omitempty"`
+}
+
+// providerAPIKey returns the api key of the provider called name.
+func (c *Profile) providerAPIKey(name string) (string, error) {
+	provider := c.Providers[name]
+	if c.overridden("providers." + name + ".api_key") {
+		return provider.APIKey, nil
+	}
+	if provider.APIKeyCommand != "" {
+		return secret.Command(context.Background(), provider.APIKeyCommand)
+	}
+	return c.secretValue("providers."+name+".api_key", provider.APIKey)
+}
+
+// providerStreamer builds the streamer for a provider of the given type.
+// azure configures a provider of type azure and may be nil otherwise.
+func (c *Profile) providerStreamer(kind, endpoint string, azure *AzureConfig, apiKey func() (string, error)) (chat.Streamer, error) {
+	switch kind {
+	case "", ProviderOpenAI:
+		key, err := apiKey()
+		if err != nil {
+			return nil, err
+		}
+		return c.openAIStreamer(endpoint, key)
+	case ProviderAzure:
+		return c.azureStreamer(endpoint, azure, apiKey)
+	case ProviderGemini:
+		key, err := apiKey()
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			return nil, fmt.Errorf("the gemini provider needs an api key")
+		}
+		client, err := c.httpClient(key)
+		if err != nil {
+			return nil, err
+		}
+		streamer := chat.NewGeminiStreamer(endpoint, key, client)
+		streamer.Headers = c.Headers
+		return streamer, nil
+	case ProviderFake:
+		return c.fakeStreamer()
+	default:
+		return nil, fmt.Errorf("unknown provider %q", kind)
+	}
+}
+
+// openAIStreamer builds the streamer for the OpenAI provider.
+func (c *Profile) openAIStreamer(endpoint, apiKey string) (chat.Streamer, error) {
+	var auth []option.RequestOption
+	if endpoint != "" {
+		auth = append(auth, option.WithBaseURL(endpoint))
+	}
+	if apiKey != "" {
+		auth = append(auth, option.WithAPIKey(apiKey))
+	}
+
+	opts, err := c.clientOptions(auth, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	client := openai.NewClient(opts...)
+	return chat.NewOpenAIStreamer(client), nil
+}
+
+// clientOptions returns the options of an OpenAI client that sends requests
+// through the profile's transport. auth sets the endpoint and credentials of
+// the provider, and secrets are kept out of the debug log.
+func (c *Profile) clientOptions(auth []option.RequestOption, secrets ...string) ([]option.RequestOption, error) {
+	httpClient, err := c.httpClient(secrets...)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []option.RequestOption{
+		option.WithHTTPClient(httpClient),
+	}
+
+	if c.ReplayDir != "" {
+		// a request without a recorded response fails right away
+		opts = append(opts, option.WithMaxRetries(0))
+	}
+
+	opts = append(opts, auth...)
+
+	for name, value := range c.Headers {
+		opts = append(opts, option.WithHeader(name, value))
+	}
+	return opts, nil
+}
+
+// httpClient returns the client sending requests through the profile's
+// transport, which logs them in debug mode with secrets redacted.
+func (c *Profile) httpClient(secrets ...string) (*http.Client, error) {
+	transport, err := c.transport()
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{
+		Timeout:   0,
+		Transport: transport,
+	}
+
+	if c.Debug {
+		log := c.DebugLog
+		if log == nil {
+			log = &DebugLog{writer: os.Stderr}
+		}
+		for _, secret := range secrets {
+			log.addSecret(secret)
+		}
+		httpClient.Transport = loggingRoundTripper{inner: httpClient.Transport, log: log}
+	}
+	return httpClient, nil
+}
+
+// fakeStreamer builds the streamer for the fake provider.
+func (c *Profile) fakeStreamer() (chat.Streamer, error) {
+	fake := c.Fake
+	if fake == nil {
+		fake = &FakeConfig{}
+	}
+	if err := chat.ValidateFakeFailure(fake.Failure); err != nil {
+		return nil, err
+	}
+	return &chat.FakeStreamer{
+		Responses: fake.Responses,
+		Delay:     time.Duration(fake.Delay),
+		Failure:   fake.Failure,
+	}, nil
+}
+
+// router sends requests whose model is prefixed with the name of one of the
+// profile's providers to that provider, and all others to the fallback. The
+// streamers of the providers are built when they are first used.
+type router struct {
+	profile  *Profile
+	fallback chat.Streamer
+
+	mu        sync.Mutex
+	streamers map[string]chat.Streamer
+}
+
+func (r *router) ChatStream(ctx context.Context, request chat.Input, onData func(message string) error) error {
+	name, model, ok := strings.Cut(request.Model, "/")
+	if !ok || r.profile.Providers[name] == nil {
+		return r.fallback.ChatStream(ctx, request, onData)
+	}
+
+	streamer, err := r.streamer(name)
+	if err != nil {
+		return err
+	}
+	request.Model = model
+	return streamer.ChatStream(ctx, request, onData)
+}
+
+func (r *router) streamer(name string) (chat.Streamer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if streamer, ok := r.streamers[name]; ok {
+		return streamer, nil
+	}
+
+	provider := r.profile.Providers[name]
+	streamer, err := r.profile.providerStreamer(provider.Type, provider.Endpoint, provider.Azure, func() (string, error) {
+		return r.profile.providerAPIKey(name)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: %w", name, err)
+	}
+	r.streamers[name] = streamer
+	return streamer, nil
+}
@@ -0,0 +1,208 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// the sources of a setting, from the highest precedence to the lowest
+const (
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceProject = "project"
+	SourceProfile = "profile"
+	SourceDefault = "default"
+)
+
+// getenv is replaced in tests.
+var getenv = os.Getenv
+
+// Explain returns the effective value of every setting and its source. A
+// setting comes from the first of these that sets it: ListFlags and Flags,
+// the key's environment variable, the project, the profile and the key's
+// default.
+// Keys with a "*" segment are listed once for every name the profile or the
+// flags use.
+func (c *Profile) Explain() ([]Setting, error) {
+	return c.explainAll(true)
+}
+
+// explainAll is Explain, but reads the secrets stored in the profile's
+// secret store only if readSecrets is set. Otherwise the stored secrets are
+// left empty.
+func (c *Profile) explainAll(readSecrets bool) ([]Setting, error) {
+	var settings []Setting
+	for _, key := range Keys {
+		for _, name := range c.explainNames(key.Name) {
+			setting, err := c.explain(key, name, readSecrets)
+			if err != nil {
+				return nil, err
+			}
+			settings = append(settings, setting)
+		}
+	}
+
+	// the default model depends on the effective provider
+	var model *Setting
+	for i := range settings {
+		switch settings[i].Name {
+		case "model":
+			model = &settings[i]
+		case "provider":
+			if model != nil && model.Source == SourceDefault {
+				model.Value = defaultModel(settings[i].Value)
+			}
+		}
+	}
+	return settings, nil
+}
+
+// explainNames returns the names of the settings matching pattern in the
+// profile and the flags.
+func (c *Profile) explainNames(pattern string) []string {
+	names := c.expand(pattern)
+	if !strings.Contains(pattern, "*") {
+		return names
+	}
+	seen := map[string]bool{}
+	for _, name := range names {
+		seen[name] = true
+	}
+	for name := range c.Flags {
+		if matchPath(pattern, name) && !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// explain returns the effective setting called name of key. A secret that
+// is not overridden is read from the secret store only if readSecrets is set.
+func (c *Profile) explain(key *Key, name string, readSecrets bool) (Setting, error) {
+	if items, ok := c.ListFlags[name]; ok {
+		if err := key.ValidateList(items); err != nil {
+			return Setting{}, err
+		}
+		return Setting{Key: key, Name: name, Value: joinList(items), Items: items, IsSet: true, Source: SourceFlag}, nil
+	}
+	if value, ok := c.Flags[name]; ok {
+		if err := key.Validate(value); err != nil {
+			return Setting{}, err
+		}
+		return Setting{Key: key, Name: name, Value: value, IsSet: true, Source: SourceFlag}, nil
+	}
+
+	if key.Env != "" {
+		if value := strings.TrimSpace(getenv(key.Env)); value != "" {
+			if err := key.Validate(value); err != nil {
+				return Setting{}, fmt.Errorf("%s: %w", key.Env, err)
+			}
+			return Setting{Key: key, Name: name, Value: value, IsSet: true, Source: SourceEnv, Origin: key.Env}, nil
+		}
+	}
+
+	if value := c.projectValue(name); value != "" {
+		return Setting{Key: key, Name: name, Value: value, IsSet: true, Source: SourceProject, Origin: c.Project.Path}, nil
+	}
+
+	if key.Secret && !readSecrets {
+		return Setting{Key: key, Name: name}, nil
+	}
+	setting, err := c.Get(name)
+	if err != nil {
+		return Setting{}, err
+	}
+	if setting.IsSet {
+		setting.Source = SourceProfile
+		setting.Origin = c.Name()
+	} else {
+		setting.Source = SourceDefault
+	}
+	return setting, nil
+}
+
+// projectValue returns the project's value of the setting called name, or
+// an empty string if the project does not set it.
+func (c *Profile) projectValue(name string) string {
+	if c.Project == nil {
+		return ""
+	}
+	switch name {
+	case "model":
+		return c.Project.Model
+	}
+	return ""
+}
+
+// Resolve returns a copy of the profile holding the effective value of
+// every setting, see Explain. Requests should be made with the resolved
+// profile, while the profile itself is the one to change and write.
+func (c *Profile) Resolve() (*Profile, error) {
+	// only the overrides are copied, and the stored secrets are read when a
+	// request needs them, so the secret store is not opened for nothing
+	settings, err := c.explainAll(false)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := c.clone()
+	if err != nil {
+		return nil, err
+	}
+	resolved.sources = map[string]string{}
+	for _, setting := range settings {
+		resolved.sources[setting.Name] = setting.Source
+		switch setting.Source {
+		case SourceFlag, SourceEnv, SourceProject:
+			// overrides are never written to the secret store
+			err := setPath(reflect.ValueOf(resolved).Elem(), strings.Split(setting.Name, "."), func(field reflect.Value) error {
+				if setting.Items != nil {
+					field.Set(reflect.ValueOf(append([]string(nil), setting.Items...)))
+					return nil
+				}
+				return parseInto(field, setting.Value)
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return resolved, nil
+}
+
+// overridden reports whether the setting called name of a resolved profile
+// comes from a flag or the environment, which take precedence over the
+// profile's secret store and key commands.
+func (c *Profile) overridden(name string) bool {
+	source := c.sources[name]
+	return source == SourceFlag || source == SourceEnv
+}
+
+// clone returns a deep copy of the profile.
+func (c *Profile) clone() (*Profile, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var stored Profile
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+
+	// the runtime fields are shared, the stored ones are copied
+	cloned := *c
+	dst := reflect.ValueOf(&cloned).Elem()
+	src := reflect.ValueOf(&stored).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		if field.IsExported() && jsonName(field) != "-" {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	return &cloned, nil
+}
@@ -0,0 +1,131 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/project"
+)
+
+func useEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	old := getenv
+	getenv = func(name string) string { return env[name] }
+	t.Cleanup(func() { getenv = old })
+}
+
+func TestResolve(t *testing.T) {
+	useEnv(t, map[string]string{
+		"HLP_MODEL":      "env-model",
+		"OPENAI_API_KEY": "sk-env",
+		"HLP_TIMEOUT":    "10s",
+	})
+
+	timeout := Duration(time.Minute)
+	p := &Profile{
+		DefaultModel:  "profile-model",
+		APIKeyCommand: "echo sk-command",
+		Timeout:       &timeout,
+		IdleTimeout:   Duration(5 * time.Second),
+		Headers:       map[string]string{"X-Team": "infra"},
+		Project:       &project.Config{Path: "/repo/.hlp.toml", Model: "project-model"},
+		Flags:         map[string]string{"timeout": "20s", "headers.X-Trace": "on"},
+	}
+
+	settings, err := p.Explain()
+	require.NoError(t, err)
+	sources := map[string]string{}
+	for _, setting := range settings {
+		sources[setting.Name] = setting.Source
+	}
+	assert.Equal(t, SourceFlag, sources["timeout"])
+	assert.Equal(t, SourceFlag, sources["headers.X-Trace"])
+	assert.Equal(t, SourceEnv, sources["model"])
+	assert.Equal(t, SourceEnv, sources["openai_api_key"])
+	assert.Equal(t, SourceProfile, sources["idle_timeout"])
+	assert.Equal(t, SourceProfile, sources["headers.X-Team"])
+	assert.Equal(t, SourceDefault, sources["provider"])
+
+	resolved, err := p.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "env-model", resolved.Model())
+	assert.Equal(t, 20*time.Second, resolved.RequestTimeout())
+	assert.Equal(t, map[string]string{"X-Team": "infra", "X-Trace": "on"}, resolved.Headers)
+	key, err := resolved.APIKey()
+	require.NoError(t, err)
+	assert.Equal(t, "sk-env", key)
+
+	// the profile itself is unchanged
+	assert.Equal(t, "profile-model", p.DefaultModel)
+	assert.Equal(t, Duration(time.Minute), *p.Timeout)
+	assert.Len(t, p.Headers, 1)
+	assert.Empty(t, p.OpenAIAPIKey)
+}
+
+func TestResolveSkipsSecretStore(t *testing.T) {
+	tmp := t.TempDir()
+	defer func(old func() string) { dir = old }(dir)
+	dir = func() string { return tmp }
+
+	// any read of the secret store fails
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, "secrets.enc"), []byte("corrupted"), 0o600))
+	useEnv(t, map[string]string{"OPENAI_API_KEY": "sk-env"})
+	p := &Profile{SecretBackend: "file", Providers: map[string]*ProviderConfig{"local": {Type: ProviderFake}}}
+
+	_, err := p.Explain()
+	assert.Error(t, err)
+
+	resolved, err := p.Resolve()
+	require.NoError(t, err)
+	key, err := resolved.APIKey()
+	require.NoError(t, err)
+	assert.Equal(t, "sk-env", key)
+}
+
+func TestResolveListFlags(t *testing.T) {
+	useEnv(t, nil)
+
+	// a path containing a comma stays one path
+	cert := filepath.Join(t.TempDir(), "corp,root.pem")
+	require.NoError(t, os.WriteFile(cert, nil, 0o600))
+	p := &Profile{CACerts: []string{"/etc/ssl/stored.pem"}, ListFlags: map[string][]string{"ca_certs": {cert}}}
+	resolved, err := p.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, []string{cert}, resolved.CACerts)
+	assert.Equal(t, []string{"/etc/ssl/stored.pem"}, p.CACerts)
+
+	p.ListFlags["ca_certs"] = []string{cert, cert + ".missing"}
+	_, err = p.Resolve()
+	var invalid *InvalidValueError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, cert+".missing", invalid.Value)
+}
+
+func TestResolveProject(t *testing.T) {
+	useEnv(t, nil)
+
+	p := &Profile{
+		DefaultModel: "profile-model",
+		Project:      &project.Config{Path: "/repo/.hlp.toml", Model: "project-model"},
+	}
+	resolved, err := p.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "project-model", resolved.Model())
+
+	p.Project = nil
+	resolved, err = p.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "profile-model", resolved.Model())
+}
+
+func TestResolveInvalid(t *testing.T) {
+	useEnv(t, map[string]string{"HLP_TIMEOUT": "soon"})
+	_, err := (&Profile{}).Resolve()
+	var invalid *InvalidValueError
+	assert.ErrorAs(t, err, &invalid)
+	assert.ErrorContains(t, err, "HLP_TIMEOUT")
+}
@@ -0,0 +1,184 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/yiblet/hlp/secret"
+)
+
+// secretName is the name of the profile's secret key in its secret store.
+func (c *Profile) secretName(key string) string {
+	return c.Name() + "/" + key
+}
+
+// SecretStore returns the store holding the profile's secrets, or nil if
+// they are kept in the profile itself.
+func (c *Profile) SecretStore() (secret.Store, error) {
+	if c.SecretBackend == "" {
+		return nil, nil
+	}
+	return secret.New(c.SecretBackend, Dir())
+}
+
+// APIKey returns the api key of the profile. It is the output of
+// APIKeyCommand if one is set, then the key in the secret store, and
+// finally the key stored in the profile. A key passed by flag or environment
+// variable to a resolved profile takes precedence over all of them.
+func (c *Profile) APIKey() (string, error) {
+	if c.overridden("openai_api_key") {
+		return c.OpenAIAPIKey, nil
+	}
+	if c.APIKeyCommand != "" {
+		return secret.Command(context.Background(), c.APIKeyCommand)
+	}
+	return c.secretValue("openai_api_key", c.OpenAIAPIKey)
+}
+
+// geminiAPIKey returns the api key of a profile with the gemini provider. It
+// is looked up like APIKey, but in gemini_api_key.
+func (c *Profile) geminiAPIKey() (string, error) {
+	if c.overridden("gemini_api_key") {
+		return c.GeminiAPIKey, nil
+	}
+	if c.APIKeyCommand != "" {
+		return secret.Command(context.Background(), c.APIKeyCommand)
+	}
+	return c.secretValue("gemini_api_key", c.GeminiAPIKey)
+}
+
+// SetAPIKey stores key in the profile's secret store, or in the profile if
+// it has none. The profile still has to be written afterwards.
+func (c *Profile) SetAPIKey(key string) error {
+	return c.setSecretValue("openai_api_key", key, &c.OpenAIAPIKey)
+}
+
+// secretValue returns the secret key from the secret store, or stored if the
+// store does not have it.
+func (c *Profile) secretValue(key, stored string) (string, error) {
+	store, err := c.SecretStore()
+	if err != nil || store == nil {
+		return stored, err
+	}
+	value, err := store.Get(c.secretName(key))
+	var notFound *secret.NotFoundError
+	if errors.As(err, &notFound) {
+		return stored, nil
+	}
+	return value, err
+}
+
+// setSecretValue stores the secret key in the secret store, or in field if
+// the profile has none.
+func (c *Profile) setSecretValue(key, value string, field *string) error {
+	store, err := c.SecretStore()
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		*field = value
+		return nil
+	}
+
+	if value == "" {
+		err = store.Delete(c.secretName(key))
+	} else {
+		err = store.Set(c.secretName(key), value)
+	}
+	if err != nil {
+		return err
+	}
+	*field = ""
+	return nil
+}
+
+// secretFields returns the secrets of the profile by key, pointing to the
+// fields that hold them when there is no secret store.
+func (c *Profile) secretFields() map[string]*string {
+	fields := map[string]*string{"openai_api_key": &c.OpenAIAPIKey, "gemini_api_key": &c.GeminiAPIKey}
+	for name, provider := range c.Providers {
+		if provider != nil {
+			fields["providers."+name+".api_key"] = &provider.APIKey
+		}
+	}
+	return fields
+}
+
+// SetSecretBackend switches the profile to another secret backend and moves
+// its secrets there. An empty backend keeps the secrets in the profile. The
+// profile still has to be written afterwards.
+func (c *Profile) SetSecretBackend(backend string) error {
+	if backend != "" {
+		if _, err := secret.New(backend, Dir()); err != nil {
+			return err
+		}
+	}
+	if backend == c.SecretBackend {
+		return nil
+	}
+
+	old, err := c.SecretStore()
+	if err != nil {
+		return err
+	}
+
+	fields := c.secretFields()
+	keys := make([]string, 0, len(fields))
+	values := map[string]string{}
+	for key, field := range fields {
+		keys = append(keys, key)
+		value, err := c.secretValue(key, *field)
+		if err != nil {
+			return err
+		}
+		values[key] = value
+	}
+	sort.Strings(keys)
+
+	c.SecretBackend = backend
+	for _, key := range keys {
+		if values[key] == "" {
+			continue
+		}
+		if err := c.setSecretValue(key, values[key], fields[key]); err != nil {
+			return err
+		}
+		if old != nil {
+			if err := old.Delete(c.secretName(key)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Masked returns a copy of the profile whose secrets are masked, for
+// printing.
+func (c *Profile) Masked() *Profile {
+	masked := *c
+	masked.OpenAIAPIKey = secret.Mask(c.OpenAIAPIKey)
+	masked.GeminiAPIKey = secret.Mask(c.GeminiAPIKey)
+	if len(c.Headers) > 0 {
+		masked.Headers = make(map[string]string, len(c.Headers))
+		for name, value := range c.Headers {
+			if secretHeaders[http.CanonicalHeaderKey(name)] {
+				value = secret.Mask(value)
+			}
+			masked.Headers[name] = value
+		}
+	}
+	if len(c.Providers) > 0 {
+		masked.Providers = make(map[string]*ProviderConfig, len(c.Providers))
+		for name, provider := range c.Providers {
+			if provider != nil {
+				copied := *provider
+				copied.APIKey = secret.Mask(provider.APIKey)
+				provider = &copied
+			}
+			masked.Providers[name] = provider
+		}
+	}
+	return &masked
+}
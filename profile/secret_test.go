@@ -0,0 +1,46 @@
+package profile
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMasked(t *testing.T) {
+	p := &Profile{
+		OpenAIAPIKey: "sk-0123456789abcdef",
+		Headers:      map[string]string{"authorization": "Bearer 0123456789", "X-Team": "infra"},
+	}
+
+	masked := p.Masked()
+	assert.Equal(t, "********cdef", masked.OpenAIAPIKey)
+	assert.Equal(t, map[string]string{"authorization": "********6789", "X-Team": "infra"}, masked.Headers)
+
+	// the profile itself is untouched
+	assert.Equal(t, "sk-0123456789abcdef", p.OpenAIAPIKey)
+	assert.Equal(t, "Bearer 0123456789", p.Headers["authorization"])
+}
+
+func TestAPIKey(t *testing.T) {
+	key, err := (&Profile{OpenAIAPIKey: "sk-stored"}).APIKey()
+	require.NoError(t, err)
+	assert.Equal(t, "sk-stored", key)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	key, err = (&Profile{OpenAIAPIKey: "sk-stored", APIKeyCommand: "echo sk-command"}).APIKey()
+	require.NoError(t, err)
+	assert.Equal(t, "sk-command", key)
+
+	_, err = (&Profile{APIKeyCommand: "exit 1"}).APIKey()
+	assert.Error(t, err)
+}
+
+func TestSetSecretBackendRejectsUnknown(t *testing.T) {
+	p := &Profile{OpenAIAPIKey: "sk-stored"}
+	assert.ErrorContains(t, p.SetSecretBackend("vault"), "unknown secret backend")
+	assert.Equal(t, "sk-stored", p.OpenAIAPIKey)
+}
@@ -0,0 +1,383 @@
+package profile
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Setting is the value of a key in a profile.
+type Setting struct {
+	Key *Key
+	// Name is the name of the setting, which differs from the key's name for
+	// keys with a "*" segment.
+	Name  string
+	Value string
+	// Items are the items of a list setting from ListFlags, which Value
+	// shows joined.
+	Items []string
+	// IsSet reports whether the profile sets the value. Otherwise Value is
+	// the key's default.
+	IsSet bool
+	// Source is where the value comes from, such as SourceEnv, and Origin
+	// names it, such as the environment variable. Only Explain sets them.
+	Source string
+	Origin string
+}
+
+// resolve returns the key called name and the canonical name of the
+// setting, which replaces an alias by the key's name.
+func resolve(name string) (*Key, string, error) {
+	key, err := LookupKey(name)
+	if err != nil {
+		return nil, "", err
+	}
+	if !matchPath(key.Name, name) {
+		name = key.Name
+	}
+	return key, name, nil
+}
+
+// Get returns the setting called name. A secret is read from the profile's
+// secret store.
+func (c *Profile) Get(name string) (Setting, error) {
+	key, name, err := resolve(name)
+	if err != nil {
+		return Setting{}, err
+	}
+
+	setting := Setting{Key: key, Name: name, Value: key.Default}
+	value, ok := getPath(reflect.ValueOf(c).Elem(), strings.Split(name, "."))
+	if key.Secret {
+		value, err = c.secretValue(name, value)
+		if err != nil {
+			return Setting{}, err
+		}
+		ok = value != ""
+	}
+	if ok {
+		setting.Value = value
+		setting.IsSet = true
+	}
+	return setting, nil
+}
+
+// Set validates value and stores it as the setting called name. A secret is
+// written to the profile's secret store. The profile still has to be
+// written afterwards.
+func (c *Profile) Set(name, value string) error {
+	key, name, err := resolve(name)
+	if err != nil {
+		return err
+	}
+	if err := key.Validate(value); err != nil {
+		return err
+	}
+
+	switch {
+	case name == "secret_backend":
+		if value == "profile" {
+			value = ""
+		}
+		return c.SetSecretBackend(value)
+	case key.Secret:
+		return setPath(reflect.ValueOf(c).Elem(), strings.Split(name, "."), func(field reflect.Value) error {
+			return c.setSecretValue(name, value, field.Addr().Interface().(*string))
+		})
+	default:
+		return setPath(reflect.ValueOf(c).Elem(), strings.Split(name, "."), func(field reflect.Value) error {
+			return parseInto(field, value)
+		})
+	}
+}
+
+// Unset removes the setting called name from the profile, so that its
+// default applies again. The profile still has to be written afterwards.
+func (c *Profile) Unset(name string) error {
+	key, name, err := resolve(name)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case name == "secret_backend":
+		return c.SetSecretBackend("")
+	case key.Secret:
+		path := strings.Split(name, ".")
+		if _, ok := getValue(reflect.ValueOf(c).Elem(), path[:len(path)-1]); !ok {
+			// do not create a provider just to unset its key
+			return nil
+		}
+		err := setPath(reflect.ValueOf(c).Elem(), path, func(field reflect.Value) error {
+			return c.setSecretValue(name, "", field.Addr().Interface().(*string))
+		})
+		if err != nil {
+			return err
+		}
+		unsetPath(reflect.ValueOf(c).Elem(), path)
+		return nil
+	default:
+		unsetPath(reflect.ValueOf(c).Elem(), strings.Split(name, "."))
+		return nil
+	}
+}
+
+// List returns every setting of the profile, including the keys it does not
+// set. Keys with a "*" segment are listed once for every name the profile
+// uses.
+func (c *Profile) List() ([]Setting, error) {
+	var settings []Setting
+	for _, key := range Keys {
+		for _, name := range c.expand(key.Name) {
+			setting, err := c.Get(name)
+			if err != nil {
+				return nil, err
+			}
+			settings = append(settings, setting)
+		}
+	}
+	return settings, nil
+}
+
+// expand returns the names of the settings matching pattern.
+func (c *Profile) expand(pattern string) []string {
+	prefix, rest, ok := strings.Cut(pattern, "*")
+	if !ok {
+		return []string{pattern}
+	}
+
+	value, exists := getValue(reflect.ValueOf(c).Elem(), strings.Split(strings.TrimSuffix(prefix, "."), "."))
+	if !exists || value.Kind() != reflect.Map {
+		return nil
+	}
+	var names []string
+	for _, mapKey := range value.MapKeys() {
+		names = append(names, prefix+mapKey.String()+rest)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// jsonName returns the name of a struct field in the profile's JSON.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+// child returns the field or map entry called name of v, which must be a
+// struct or a map.
+func child(v reflect.Value, name string) (reflect.Value, bool) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if jsonName(v.Type().Field(i)) == name {
+				return v.Field(i), true
+			}
+		}
+	case reflect.Map:
+		entry := v.MapIndex(reflect.ValueOf(name))
+		return entry, entry.IsValid()
+	}
+	return reflect.Value{}, false
+}
+
+// getValue follows path from v through structs, pointers and maps.
+func getValue(v reflect.Value, path []string) (reflect.Value, bool) {
+	for _, name := range path {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		var ok bool
+		if v, ok = child(v, name); !ok {
+			return reflect.Value{}, false
+		}
+	}
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	return v, true
+}
+
+// getPath returns the formatted value at path and whether it is set.
+func getPath(v reflect.Value, path []string) (string, bool) {
+	value, ok := getValue(v, path)
+	if !ok || value.IsZero() {
+		if ok && value.Kind() == reflect.Int64 && value.Type() == reflect.TypeOf(Duration(0)) && isPointerField(v, path) {
+			// a pointer to a zero duration, such as a disabled timeout
+			return format(value), true
+		}
+		return "", false
+	}
+	return format(value), true
+}
+
+// isPointerField reports whether the field at path is a non-nil pointer.
+func isPointerField(v reflect.Value, path []string) bool {
+	parent, ok := getValue(v, path[:len(path)-1])
+	if !ok {
+		return false
+	}
+	field, ok := child(parent, path[len(path)-1])
+	return ok && field.Kind() == reflect.Pointer && !field.IsNil()
+}
+
+// setPath follows path from v, allocating pointers and map entries on the
+// way, and calls set with the addressable field at its end.
+func setPath(v reflect.Value, path []string, set func(field reflect.Value) error) error {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if len(path) == 0 {
+		return set(v)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		field, ok := child(v, path[0])
+		if !ok {
+			return fmt.Errorf("unknown field %s", path[0])
+		}
+		return setPath(field, path[1:], set)
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		mapKey := reflect.ValueOf(path[0])
+		entry := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(mapKey); existing.IsValid() {
+			entry.Set(existing)
+		}
+		if err := setPath(entry, path[1:], set); err != nil {
+			return err
+		}
+		v.SetMapIndex(mapKey, entry)
+		return nil
+	default:
+		return fmt.Errorf("cannot set %s in a %s", path[0], v.Kind())
+	}
+}
+
+// unsetPath resets the field at path to its zero value, deleting map
+// entries that end up empty.
+func unsetPath(v reflect.Value, path []string) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		field, ok := child(v, path[0])
+		if !ok {
+			return
+		}
+		if len(path) == 1 {
+			field.Set(reflect.Zero(field.Type()))
+			return
+		}
+		unsetPath(field, path[1:])
+	case reflect.Map:
+		mapKey := reflect.ValueOf(path[0])
+		entry := v.MapIndex(mapKey)
+		if !entry.IsValid() {
+			return
+		}
+		if len(path) > 1 {
+			unsetPath(entry, path[1:])
+			if !isEmpty(entry) {
+				return
+			}
+		}
+		v.SetMapIndex(mapKey, reflect.Value{})
+	}
+}
+
+func isEmpty(v reflect.Value) bool {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	return v.IsZero()
+}
+
+// parseInto parses value into the field according to its type.
+func parseInto(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(Duration(0)) {
+		d, err := ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Slice:
+		field.Set(reflect.ValueOf(splitList(value)))
+	case reflect.Map:
+		pairs, err := splitMap(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(pairs))
+	default:
+		return fmt.Errorf("cannot set a %s", field.Kind())
+	}
+	return nil
+}
+
+// format formats the value of a field for printing.
+func format(v reflect.Value) string {
+	switch value := v.Interface().(type) {
+	case Duration:
+		return value.String()
+	case []string:
+		return joinList(value)
+	case map[string]string:
+		pairs := make([]string, 0, len(value))
+		for name, value := range value {
+			pairs = append(pairs, name+"="+value)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	}
+	return fmt.Sprint(v.Interface())
+}
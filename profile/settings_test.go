@@ -0,0 +1,163 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/chat"
+)
+
+func TestSetGetUnset(t *testing.T) {
+	for _, name := range []string{"audit", "metrics"} {
+		chat.RegisterMiddleware(name, func(next chat.Streamer) chat.Streamer { return next })
+	}
+	p := &Profile{}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"model", "gpt-4o"},
+		{"timeout", "0s"},
+		{"idle_timeout", "30s"},
+		{"proxy", "socks5://localhost:1080"},
+		{"headers.X-Team", "infra"},
+		{"middleware", "audit,metrics"},
+		{"fake.responses", `yes\, sure,no`},
+		{"cache.enabled", "true"},
+		{"cache.max_size", "1024"},
+		{"fake.failure", "truncate"},
+		{"providers.local.type", "fake"},
+		{"providers.local.endpoint", "http://localhost:11434/v1"},
+		{"providers.local.api_key", "sk-local"},
+	}
+	for _, tc := range tests {
+		require.NoError(t, p.Set(tc.name, tc.value), tc.name)
+		setting, err := p.Get(tc.name)
+		require.NoError(t, err, tc.name)
+		assert.True(t, setting.IsSet, tc.name)
+		assert.Equal(t, tc.value, setting.Value, tc.name)
+	}
+
+	assert.Equal(t, []string{"audit", "metrics"}, p.Middleware)
+	assert.Equal(t, []string{"yes, sure", "no"}, p.Fake.Responses)
+	assert.Equal(t, Duration(0), *p.Timeout)
+	assert.Equal(t, "sk-local", p.Providers["local"].APIKey)
+
+	for _, tc := range tests {
+		require.NoError(t, p.Unset(tc.name), tc.name)
+		setting, err := p.Get(tc.name)
+		require.NoError(t, err, tc.name)
+		assert.False(t, setting.IsSet, tc.name)
+	}
+	assert.Nil(t, p.Timeout)
+	assert.Empty(t, p.Headers)
+	assert.Empty(t, p.Providers)
+}
+
+func TestGetDefault(t *testing.T) {
+	setting, err := (&Profile{}).Get("timeout")
+	require.NoError(t, err)
+	assert.False(t, setting.IsSet)
+	assert.Equal(t, DefaultTimeout.String(), setting.Value)
+}
+
+func TestSetAlias(t *testing.T) {
+	p := &Profile{}
+	require.NoError(t, p.Set("openai_api_endpoint", "https://example.com/v1"))
+	assert.Equal(t, "https://example.com/v1", p.OpenAIAPIEndpoint)
+
+	setting, err := p.Get("openai_api_endpoint")
+	require.NoError(t, err)
+	assert.Equal(t, "endpoint", setting.Name)
+}
+
+func TestSetInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"endpoint", "localhost"},
+		{"endpoint", "ftp://example.com"},
+		{"provider", "unknown"},
+		{"timeout", "-1s"},
+		{"timeout", "soon"},
+		{"cache.enabled", "maybe"},
+		{"cache.max_size", "-1"},
+		{"fake.failure", "explode"},
+		{"ca_certs", "/does/not/exist.pem"},
+		{"middleware", "no-such-middleware"},
+		{"providers.local.type", "unknown"},
+	}
+	for _, tc := range tests {
+		p := &Profile{}
+		err := p.Set(tc.name, tc.value)
+		var invalid *InvalidValueError
+		assert.True(t, errors.As(err, &invalid), "%s=%s: %v", tc.name, tc.value, err)
+		assert.Empty(t, p.Providers)
+	}
+
+	var unknown *UnknownKeyError
+	assert.True(t, errors.As((&Profile{}).Set("bogus", "1"), &unknown))
+	assert.True(t, errors.As((&Profile{}).Set("providers..endpoint", "http://x"), &unknown))
+}
+
+func TestList(t *testing.T) {
+	p := &Profile{DefaultModel: "gpt-4o", Headers: map[string]string{"B": "2", "A": "1"}}
+	settings, err := p.List()
+	require.NoError(t, err)
+
+	byName := map[string]Setting{}
+	var names []string
+	for _, setting := range settings {
+		byName[setting.Name] = setting
+		names = append(names, setting.Name)
+	}
+	assert.True(t, byName["model"].IsSet)
+	assert.False(t, byName["provider"].IsSet)
+	assert.Equal(t, ProviderOpenAI, byName["provider"].Value)
+	assert.Subset(t, names, []string{"headers.A", "headers.B"})
+	assert.NotContains(t, names, "providers.*.endpoint")
+}
+
+func TestRouter(t *testing.T) {
+	record := func(models *[]string) chat.Streamer {
+		return chat.StreamerFunc(func(ctx context.Context, request chat.Input, onData func(string) error) error {
+			*models = append(*models, request.Model)
+			return nil
+		})
+	}
+
+	var fallback, local []string
+	r := &router{
+		profile:   &Profile{Providers: map[string]*ProviderConfig{"local": {}}},
+		fallback:  record(&fallback),
+		streamers: map[string]chat.Streamer{"local": record(&local)},
+	}
+	for _, model := range []string{"gpt-4o", "local/llama3", "other/model"} {
+		require.NoError(t, r.ChatStream(context.Background(), chat.Input{Model: model}, nil))
+	}
+	assert.Equal(t, []string{"gpt-4o", "other/model"}, fallback)
+	assert.Equal(t, []string{"llama3"}, local)
+}
+
+func TestClientRoutesToProvider(t *testing.T) {
+	p := &Profile{
+		Provider:  ProviderFake,
+		Fake:      &FakeConfig{Responses: []string{"from fake"}},
+		Providers: map[string]*ProviderConfig{"alt": {Type: ProviderFake}},
+	}
+	client, err := p.Client()
+	require.NoError(t, err)
+
+	var out string
+	err = client.ChatStream(context.Background(), chat.Input{Model: "alt/any"}, func(message string) error {
+		out += message
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "from fake", out)
+}
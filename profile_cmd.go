@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/yiblet/hlp/profile"
+)
+
+type profileCmd struct {
+	List    *profileListCmd    `arg:"subcommand:list" help:"list the profiles"`
+	Create  *profileCreateCmd  `arg:"subcommand:create" help:"create an empty profile"`
+	Copy    *profileCopyCmd    `arg:"subcommand:copy" help:"copy a profile, including its secrets"`
+	Rename  *profileRenameCmd  `arg:"subcommand:rename" help:"rename a profile"`
+	Rm      *profileRmCmd      `arg:"subcommand:rm" help:"delete a profile and its secrets"`
+	Use     *profileUseCmd     `arg:"subcommand:use" help:"use a profile when --config and HLP_CONFIG are not set"`
+	Current *profileCurrentCmd `arg:"subcommand:current" help:"print the active profile"`
+}
+
+func (c *profileCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	switch {
+	case c.List != nil:
+		return c.List.Execute(ctx, config)
+	case c.Create != nil:
+		return c.Create.Execute(ctx)
+	case c.Copy != nil:
+		return c.Copy.Execute(ctx)
+	case c.Rename != nil:
+		return c.Rename.Execute(ctx)
+	case c.Rm != nil:
+		return c.Rm.Execute(ctx, config)
+	case c.Use != nil:
+		return c.Use.Execute(ctx)
+	case c.Current != nil:
+		return c.Current.Execute(ctx, config)
+	default:
+		return writeHelp(c, os.Stderr)
+	}
+}
+
+type profileListCmd struct{}
+
+func (c *profileListCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	names, err := profile.Names()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		marker := " "
+		if name == config.Name() {
+			marker = "*"
+		}
+		fmt.Printf("%s %s\n", marker, name)
+	}
+	return nil
+}
+
+type profileCreateCmd struct {
+	Name string `arg:"required,positional" help:"the name of the profile"`
+}
+
+func (c *profileCreateCmd) Execute(ctx context.Context) error {
+	if err := profile.Create(c.Name); err != nil {
+		return err
+	}
+	fmt.Printf("created profile %s\n", c.Name)
+	return nil
+}
+
+type profileCopyCmd struct {
+	Source string `arg:"required,positional" help:"the profile to copy"`
+	Target string `arg:"required,positional" help:"the name of the copy"`
+}
+
+func (c *profileCopyCmd) Execute(ctx context.Context) error {
+	if err := profile.Copy(c.Source, c.Target); err != nil {
+		return err
+	}
+	fmt.Printf("copied profile %s to %s\n", c.Source, c.Target)
+	return nil
+}
+
+type profileRenameCmd struct {
+	Source string `arg:"required,positional" help:"the profile to rename"`
+	Target string `arg:"required,positional" help:"the new name"`
+}
+
+func (c *profileRenameCmd) Execute(ctx context.Context) error {
+	if err := profile.Rename(c.Source, c.Target); err != nil {
+		return err
+	}
+	fmt.Printf("renamed profile %s to %s\n", c.Source, c.Target)
+	return nil
+}
+
+type profileRmCmd struct {
+	Name string `arg:"required,positional" help:"the profile to delete"`
+}
+
+func (c *profileRmCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	if c.Name == config.Name() && c.Name != profile.Default() {
+		return fmt.Errorf("cannot delete the profile %s while it is selected with --config or HLP_CONFIG", c.Name)
+	}
+	if err := profile.Remove(c.Name); err != nil {
+		return err
+	}
+	fmt.Printf("deleted profile %s\n", c.Name)
+	return nil
+}
+
+type profileUseCmd struct {
+	Name string `arg:"required,positional" help:"the profile to use by default"`
+}
+
+func (c *profileUseCmd) Execute(ctx context.Context) error {
+	if err := profile.Use(c.Name); err != nil {
+		return err
+	}
+	fmt.Printf("using profile %s\n", c.Name)
+	return nil
+}
+
+type profileCurrentCmd struct{}
+
+func (c *profileCurrentCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	fmt.Printf("%s\n", config.Name())
+	return nil
+}
@@ -0,0 +1,14 @@
+package project
+
+import "fmt"
+
+// ForbiddenKeyError is returned for a project file setting a key that is
+// unknown or only allowed in a profile, such as an api key or an endpoint.
+type ForbiddenKeyError struct {
+	Path string
+	Key  string
+}
+
+func (e *ForbiddenKeyError) Error() string {
+	return fmt.Sprintf("%s: %q cannot be set in a project file, only model, prompt, prompts and ignore are allowed; secrets and connection settings belong in your profile", e.Path, e.Key)
+}
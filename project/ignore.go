@@ -0,0 +1,41 @@
+package project
+
+import (
+	"path"
+	"strings"
+)
+
+// DefaultIgnore are the patterns that are always ignored.
+var DefaultIgnore = []string{".git/"}
+
+// Ignored reports whether a file found while attaching a directory is
+// skipped. rel is the slash separated path of the file relative to the
+// directory. The project may be nil.
+//
+// The patterns loosely follow .gitignore: a pattern without a slash matches
+// the name of any file or directory, one with a slash matches the whole
+// path, and one ending in a slash only matches directories.
+func (c *Config) Ignored(rel string, isDir bool) bool {
+	patterns := DefaultIgnore
+	if c != nil {
+		patterns = append(append([]string{}, DefaultIgnore...), c.Ignore...)
+	}
+
+	for _, pattern := range patterns {
+		pattern, dirOnly := strings.CutSuffix(pattern, "/")
+		if pattern == "" || (dirOnly && !isDir) {
+			continue
+		}
+
+		var ok bool
+		if strings.Contains(pattern, "/") {
+			ok, _ = path.Match(strings.TrimPrefix(pattern, "/"), rel)
+		} else {
+			ok, _ = path.Match(pattern, path.Base(rel))
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
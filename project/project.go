@@ -0,0 +1,141 @@
+// Package project reads the hlp settings a repository ships in a .hlp.toml
+// or .hlp.json file. They are layered over the user's profile and may not
+// contain secrets or connection settings, so that checking out a repository
+// can never redirect requests or leak api keys.
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/yiblet/hlp/prompt"
+)
+
+// FileNames are the names of project files, in order of precedence.
+var FileNames = []string{".hlp.toml", ".hlp.json"}
+
+// Config holds the settings of a project.
+type Config struct {
+	// Path is the file the settings were read from.
+	Path string `toml:"-" json:"-"`
+
+	// Model is the default model of the project.
+	Model string `toml:"model" json:"model,omitempty"`
+	// Prompt is the name of the prompt hlp ask uses when none is given.
+	Prompt string `toml:"prompt" json:"prompt,omitempty"`
+	// Prompts are prompt library entries, which take precedence over the
+	// user's prompts.
+	Prompts map[string]string `toml:"prompts" json:"prompts,omitempty"`
+	// Ignore are patterns of files that are skipped when attaching a
+	// directory, see Ignored.
+	Ignore []string `toml:"ignore" json:"ignore,omitempty"`
+}
+
+// Dir returns the directory of the project.
+func (c *Config) Dir() string {
+	return filepath.Dir(c.Path)
+}
+
+// Find looks for a project file in dir and its parents. It returns nil if
+// there is none.
+func Find(dir string) (*Config, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		for _, name := range FileNames {
+			path := filepath.Join(dir, name)
+			info, err := os.Stat(path)
+			if err == nil && !info.IsDir() {
+				return Load(path)
+			}
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads the project file at path. Unknown settings are rejected, with
+// a dedicated error for the ones that are only allowed in a profile.
+func Load(path string) (*Config, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{Path: path}
+	var keys []string
+	if strings.HasSuffix(path, ".json") {
+		keys, err = decodeJSON(buf, config)
+	} else {
+		keys, err = decodeTOML(buf, config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		return nil, &ForbiddenKeyError{Path: path, Key: keys[0]}
+	}
+
+	for name := range config.Prompts {
+		if err := prompt.ValidateName(name); err != nil {
+			return nil, fmt.Errorf("cannot read %s: %w", path, err)
+		}
+	}
+	return config, nil
+}
+
+// decodeTOML decodes buf into config and returns the keys it does not know.
+func decodeTOML(buf []byte, config *Config) ([]string, error) {
+	meta, err := toml.Decode(string(buf), config)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, key := range meta.Undecoded() {
+		keys = append(keys, key.String())
+	}
+	return keys, nil
+}
+
+// decodeJSON decodes buf into config and returns the keys it does not know.
+func decodeJSON(buf []byte, config *Config) ([]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for key := range raw {
+		switch key {
+		case "model", "prompt", "prompts", "ignore":
+		default:
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > 0 {
+		return keys, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.DisallowUnknownFields()
+	return nil, decoder.Decode(config)
+}
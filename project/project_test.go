@@ -0,0 +1,96 @@
+package project
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	require.NoError(t, os.MkdirAll(nested, 0755))
+
+	config, err := Find(nested)
+	require.NoError(t, err)
+	assert.Nil(t, config)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".hlp.toml"), []byte(`
+model = "gpt-4o"
+prompt = "review"
+ignore = ["*.lock", "vendor/"]
+
+[prompts]
+review = "Review this change."
+`), 0644))
+
+	config, err = Find(nested)
+	require.NoError(t, err)
+	require.NotNil(t, config)
+	assert.Equal(t, filepath.Join(root, ".hlp.toml"), config.Path)
+	assert.Equal(t, root, config.Dir())
+	assert.Equal(t, "gpt-4o", config.Model)
+	assert.Equal(t, "review", config.Prompt)
+	assert.Equal(t, map[string]string{"review": "Review this change."}, config.Prompts)
+	assert.Equal(t, []string{"*.lock", "vendor/"}, config.Ignore)
+
+	// the closest project file wins
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", ".hlp.json"), []byte(`{"model": "local/llama3"}`), 0644))
+	config, err = Find(nested)
+	require.NoError(t, err)
+	assert.Equal(t, "local/llama3", config.Model)
+}
+
+func TestLoadRejectsSecrets(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		".hlp.toml": "model = \"gpt-4o\"\nopenai_api_key = \"sk-leak\"\n",
+		".hlp.json": `{"model": "gpt-4o", "endpoint": "https://evil.example.com"}`,
+	}
+	for name, content := range tests {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+		_, err := Load(path)
+		var forbidden *ForbiddenKeyError
+		require.True(t, errors.As(err, &forbidden), "%s: %v", name, err)
+	}
+}
+
+func TestLoadRejectsInvalidPromptName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".hlp.toml")
+	require.NoError(t, os.WriteFile(path, []byte("[prompts]\n\"../x\" = \"hi\"\n"), 0644))
+
+	_, err := Load(path)
+	assert.ErrorContains(t, err, "invalid prompt name")
+}
+
+func TestIgnored(t *testing.T) {
+	config := &Config{Ignore: []string{"*.lock", "vendor/", "docs/*.md"}}
+
+	tests := []struct {
+		rel     string
+		isDir   bool
+		ignored bool
+	}{
+		{".git", true, true},
+		{"go.lock", false, true},
+		{"sub/yarn.lock", false, true},
+		{"vendor", true, true},
+		{"vendor", false, false},
+		{"docs/readme.md", false, true},
+		{"sub/docs/readme.md", false, false},
+		{"main.go", false, false},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.ignored, config.Ignored(tc.rel, tc.isDir), tc.rel)
+	}
+
+	var none *Config
+	assert.True(t, none.Ignored(".git", true))
+	assert.False(t, none.Ignored("go.lock", false))
+}
@@ -0,0 +1,25 @@
+package prompt
+
+import "fmt"
+
+type InvalidVarError struct {
+	Assignment string
+}
+
+func (e *InvalidVarError) Error() string {
+	return fmt.Sprintf("invalid variable %q: expected key=value", e.Assignment)
+}
+
+type InvalidNameError struct {
+	Name string
+}
+
+func (e *InvalidNameError) Error() string {
+	return fmt.Sprintf("invalid prompt name %q: only letters, digits, '-' and '_' are allowed", e.Name)
+}
+
+type NotFoundError struct {
+	Name string
+}
+
+func (e *NotFoundError) Error() string { return fmt.Sprintf("prompt not found: %s", e.Name) }
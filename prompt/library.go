@@ -0,0 +1,145 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Extension is the file extension of prompts stored in a Library directory.
+const Extension = ".txt"
+
+var nameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Entry describes a named prompt in a Library.
+type Entry struct {
+	Name string
+	// Builtin is true if the prompt ships with hlp.
+	Builtin bool
+	// Overridden is true if a builtin prompt has been replaced by a file in
+	// the library directory or by a project prompt.
+	Overridden bool
+	// Project is true if the prompt comes from the project's configuration.
+	Project bool
+}
+
+// Library is a collection of named prompts. Prompts are stored as files in
+// Dir and take precedence over the builtin prompts with the same name.
+// Project prompts take precedence over both.
+type Library struct {
+	Dir      string
+	Builtins map[string]string
+	Project  map[string]string
+}
+
+// NewLibrary creates a Library backed by dir containing the builtin prompts.
+func NewLibrary(dir string) *Library {
+	return &Library{Dir: dir, Builtins: Builtins}
+}
+
+// ValidateName checks that name can be used as a prompt name.
+func ValidateName(name string) error {
+	if !nameRegexp.MatchString(name) {
+		return &InvalidNameError{name}
+	}
+	return nil
+}
+
+// Path returns the path of the file that stores the prompt name.
+func (l *Library) Path(name string) string {
+	return filepath.Join(l.Dir, name+Extension)
+}
+
+// Get returns the content of the prompt name.
+func (l *Library) Get(name string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+
+	if content, ok := l.Project[name]; ok {
+		return content, nil
+	}
+
+	buf, err := os.ReadFile(l.Path(name))
+	if err == nil {
+		return string(buf), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	if content, ok := l.Builtins[name]; ok {
+		return content, nil
+	}
+	return "", &NotFoundError{name}
+}
+
+// List returns every prompt in the library sorted by name.
+func (l *Library) List() ([]Entry, error) {
+	entries := map[string]Entry{}
+	for name := range l.Builtins {
+		entries[name] = Entry{Name: name, Builtin: true}
+	}
+
+	files, err := os.ReadDir(l.Dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, file := range files {
+		name, ok := strings.CutSuffix(file.Name(), Extension)
+		if file.IsDir() || !ok || ValidateName(name) != nil {
+			continue
+		}
+		entry := entries[name]
+		entry.Name = name
+		entry.Overridden = entry.Builtin
+		entries[name] = entry
+	}
+	for name := range l.Project {
+		entry := entries[name]
+		entry.Overridden = entry.Builtin || entry.Name != ""
+		entry.Name = name
+		entry.Project = true
+		entries[name] = entry
+	}
+
+	result := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// Add stores content as the prompt name. Existing prompt files are only
+// replaced if overwrite is set.
+func (l *Library) Add(name, content string, overwrite bool) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(l.Dir, 0755); err != nil {
+		return fmt.Errorf("cannot create prompt directory: %w", err)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	file, err := os.OpenFile(l.Path(name), flags, 0644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("prompt %q already exists", name)
+		}
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(content); err != nil {
+		return err
+	}
+	return file.Close()
+}
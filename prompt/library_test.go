@@ -0,0 +1,80 @@
+package prompt_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/prompt"
+)
+
+func TestLibrary(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "prompts")
+	library := &prompt.Library{
+		Dir:      dir,
+		Builtins: map[string]string{"bash": "builtin bash"},
+	}
+
+	content, err := library.Get("bash")
+	require.NoError(t, err)
+	assert.Equal(t, "builtin bash", content)
+
+	_, err = library.Get("missing")
+	var notFound *prompt.NotFoundError
+	assert.ErrorAs(t, err, &notFound)
+
+	_, err = library.Get("../bash")
+	var invalid *prompt.InvalidNameError
+	assert.ErrorAs(t, err, &invalid)
+
+	require.NoError(t, library.Add("bash", "custom bash", false))
+	require.NoError(t, library.Add("sql", "custom sql", false))
+	assert.Error(t, library.Add("sql", "other sql", false))
+	require.NoError(t, library.Add("sql", "other sql", true))
+
+	// files that are not prompts are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.md"), nil, 0644))
+
+	content, err = library.Get("bash")
+	require.NoError(t, err)
+	assert.Equal(t, "custom bash", content)
+
+	content, err = library.Get("sql")
+	require.NoError(t, err)
+	assert.Equal(t, "other sql", content)
+
+	entries, err := library.List()
+	require.NoError(t, err)
+	assert.Equal(t, []prompt.Entry{
+		{Name: "bash", Builtin: true, Overridden: true},
+		{Name: "sql"},
+	}, entries)
+}
+
+func TestLibraryProject(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "prompts")
+	library := &prompt.Library{
+		Dir:      dir,
+		Builtins: map[string]string{"bash": "builtin bash", "sql": "builtin sql"},
+		Project:  map[string]string{"bash": "project bash", "review": "project review"},
+	}
+	require.NoError(t, library.Add("review", "user review", false))
+
+	content, err := library.Get("bash")
+	require.NoError(t, err)
+	assert.Equal(t, "project bash", content)
+
+	content, err = library.Get("review")
+	require.NoError(t, err)
+	assert.Equal(t, "project review", content)
+
+	entries, err := library.List()
+	require.NoError(t, err)
+	assert.Equal(t, []prompt.Entry{
+		{Name: "bash", Builtin: true, Overridden: true, Project: true},
+		{Name: "review", Overridden: true, Project: true},
+		{Name: "sql", Builtin: true},
+	}, entries)
+}
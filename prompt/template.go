@@ -0,0 +1,101 @@
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Vars holds the variables that are available to a prompt template.
+type Vars map[string]string
+
+// ParseVar parses a single "key=value" assignment.
+func ParseVar(assignment string) (string, string, error) {
+	key, value, ok := strings.Cut(assignment, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return "", "", &InvalidVarError{assignment}
+	}
+	return key, value, nil
+}
+
+// ReadVars reads variables from a reader with one "key=value" assignment
+// per line. Blank lines and lines starting with "#" are ignored.
+func ReadVars(reader io.Reader) (Vars, error) {
+	vars := Vars{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, err := ParseVar(line)
+		if err != nil {
+			return nil, err
+		}
+		vars[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// ReadVarsFile reads variables from the file at path. See ReadVars for the
+// file format.
+func ReadVarsFile(path string) (Vars, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadVars(file)
+}
+
+// Set parses each "key=value" assignment and stores it in vars, overriding
+// any existing values.
+func (vars Vars) Set(assignments ...string) error {
+	for _, assignment := range assignments {
+		key, value, err := ParseVar(assignment)
+		if err != nil {
+			return err
+		}
+		vars[key] = value
+	}
+	return nil
+}
+
+func lookupEnv(key string) (string, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return "", fmt.Errorf("environment variable %q is not set", key)
+	}
+	return value, nil
+}
+
+// Render executes text as a text/template with vars as its data. Variables
+// are referenced as {{.name}} and environment variables as {{env "NAME"}}.
+// Referencing an undefined variable is an error.
+func Render(text string, vars Vars) (string, error) {
+	tmpl, err := template.New("prompt").
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"env": lookupEnv}).
+		Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	if vars == nil {
+		vars = Vars{}
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, map[string]string(vars)); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
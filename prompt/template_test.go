@@ -0,0 +1,77 @@
+package prompt_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yiblet/hlp/prompt"
+)
+
+func TestRender(t *testing.T) {
+	t.Setenv("HLP_TEST_USER", "alice")
+
+	testCases := []struct {
+		name     string
+		input    string
+		vars     prompt.Vars
+		expected string
+		err      bool
+	}{
+		{
+			name:     "no variables",
+			input:    "plain text\n",
+			expected: "plain text\n",
+		},
+		{
+			name:     "variable",
+			input:    "review {{.file}} in {{.lang}}",
+			vars:     prompt.Vars{"file": "main.go", "lang": "go"},
+			expected: "review main.go in go",
+		},
+		{
+			name:     "env",
+			input:    `hello {{env "HLP_TEST_USER"}}`,
+			expected: "hello alice",
+		},
+		{
+			name:  "undefined variable",
+			input: "review {{.file}}",
+			err:   true,
+		},
+		{
+			name:  "undefined env",
+			input: `{{env "HLP_TEST_UNDEFINED_VARIABLE"}}`,
+			err:   true,
+		},
+		{
+			name:  "malformed template",
+			input: "{{.file",
+			vars:  prompt.Vars{"file": "main.go"},
+			err:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := prompt.Render(tc.input, tc.vars)
+			if tc.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, output)
+			}
+		})
+	}
+}
+
+func TestReadVars(t *testing.T) {
+	input := "# comment\nfile=main.go\n\nquery = a=b\n"
+	vars, err := prompt.ReadVars(strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Equal(t, prompt.Vars{"file": "main.go", "query": " a=b"}, vars)
+
+	_, err = prompt.ReadVars(strings.NewReader("novalue\n"))
+	var invalid *prompt.InvalidVarError
+	assert.ErrorAs(t, err, &invalid)
+}
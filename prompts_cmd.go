@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/yiblet/hlp/profile"
+	"github.com/yiblet/hlp/prompt"
+)
+
+type promptsCmd struct {
+	List *promptsListCmd `arg:"subcommand:list" help:"list the prompts in the library"`
+	Show *promptsShowCmd `arg:"subcommand:show" help:"print a prompt"`
+	Edit *promptsEditCmd `arg:"subcommand:edit" help:"open a prompt in $EDITOR"`
+	Add  *promptsAddCmd  `arg:"subcommand:add" help:"add a prompt to the library"`
+}
+
+func (c *promptsCmd) Execute(ctx context.Context, config *profile.Profile) error {
+	library := config.Prompts()
+	switch {
+	case c.List != nil:
+		return c.List.Execute(ctx, library)
+	case c.Show != nil:
+		return c.Show.Execute(ctx, library)
+	case c.Edit != nil:
+		return c.Edit.Execute(ctx, library)
+	case c.Add != nil:
+		return c.Add.Execute(ctx, library)
+	default:
+		return writeHelp(c, os.Stderr)
+	}
+}
+
+type promptsListCmd struct{}
+
+func (c *promptsListCmd) Execute(ctx context.Context, library *prompt.Library) error {
+	entries, err := library.List()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		switch {
+		case entry.Project:
+			fmt.Printf("%s (project)\n", entry.Name)
+		case entry.Overridden:
+			fmt.Printf("%s (overridden)\n", entry.Name)
+		case entry.Builtin:
+			fmt.Printf("%s (builtin)\n", entry.Name)
+		default:
+			fmt.Printf("%s\n", entry.Name)
+		}
+	}
+	return nil
+}
+
+type promptsShowCmd struct {
+	Name string `arg:"required,positional" help:"the name of the prompt"`
+}
+
+func (c *promptsShowCmd) Execute(ctx context.Context, library *prompt.Library) error {
+	content, err := library.Get(c.Name)
+	if err != nil {
+		return err
+	}
+	fmt.Print(content)
+	return nil
+}
+
+type promptsEditCmd struct {
+	Name string `arg:"required,positional" help:"the name of the prompt"`
+}
+
+// Execute opens the prompt file in the editor. Builtin prompts are copied
+// into the library first so that the edit overrides them.
+func (c *promptsEditCmd) Execute(ctx context.Context, library *prompt.Library) error {
+	if err := prompt.ValidateName(c.Name); err != nil {
+		return err
+	}
+
+	path := library.Path(c.Name)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		content := library.Builtins[c.Name]
+		if err := library.Add(c.Name, content, false); err != nil {
+			return err
+		}
+	}
+
+	return openEditor(ctx, path)
+}
+
+type promptsAddCmd struct {
+	Name    string `arg:"required,positional" help:"the name of the prompt"`
+	Content string `arg:"positional" help:"the prompt, read from stdin if empty"`
+	Force   bool   `arg:"--force,-f" help:"replace the prompt if it already exists"`
+}
+
+func (c *promptsAddCmd) Execute(ctx context.Context, library *prompt.Library) error {
+	content := c.Content
+	if content == "" {
+		buf, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		content = string(buf)
+	}
+
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("prompt %q is empty", c.Name)
+	}
+
+	if err := library.Add(c.Name, content, c.Force); err != nil {
+		return err
+	}
+	fmt.Printf("prompt %s stored in %s\n", c.Name, library.Path(c.Name))
+	return nil
+}
@@ -0,0 +1,21 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"strings"
+)
+
+// Command runs a shell command such as "pass show openai" and returns its
+// standard output with surrounding whitespace removed. The command runs in sh,
+// or in cmd.exe on Windows.
+func Command(ctx context.Context, command string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := shellCommand(ctx, command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", &CommandError{Command: command, Err: err, Stderr: strings.TrimSpace(stderr.String())}
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
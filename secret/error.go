@@ -0,0 +1,30 @@
+package secret
+
+import "fmt"
+
+// NotFoundError is returned when a secret does not exist.
+type NotFoundError struct {
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("secret %s not found", e.Name)
+}
+
+// CommandError is returned when a secret command fails.
+type CommandError struct {
+	Command string
+	Err     error
+	Stderr  string
+}
+
+func (e *CommandError) Error() string {
+	if e.Stderr != "" {
+		return fmt.Sprintf("secret command %q failed: %v: %s", e.Command, e.Err, e.Stderr)
+	}
+	return fmt.Sprintf("secret command %q failed: %v", e.Command, e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,238 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/yiblet/hlp/filelock"
+)
+
+// File stores secrets in a file encrypted with AES-GCM. The key is kept in
+// a separate file that only the user can read. It keeps secrets out of the
+// profiles, which are printed and shared far more often, but it is no
+// protection against someone who can read the user's files.
+type File struct {
+	// Path is the encrypted file.
+	Path string
+	// KeyPath is the file holding the encryption key. It is created on the
+	// first write.
+	KeyPath string
+
+	mu sync.Mutex
+}
+
+// NewFile returns a File keeping its files in dir.
+func NewFile(dir string) *File {
+	return &File{
+		Path:    filepath.Join(dir, "secrets.enc"),
+		KeyPath: filepath.Join(dir, "secrets.key"),
+	}
+}
+
+func (f *File) Get(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secrets, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[name]
+	if !ok {
+		return "", &NotFoundError{Name: name}
+	}
+	return value, nil
+}
+
+func (f *File) Set(name, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	secrets, err := f.read()
+	if err != nil {
+		return err
+	}
+	secrets[name] = value
+	return f.write(secrets)
+}
+
+func (f *File) Delete(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	secrets, err := f.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[name]; !ok {
+		return nil
+	}
+	delete(secrets, name)
+	return f.write(secrets)
+}
+
+// lock keeps other processes from changing the secrets until it is
+// released, so that none of their changes is lost.
+func (f *File) lock() (func() error, error) {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		return nil, err
+	}
+	return filelock.Lock(f.Path, nil)
+}
+
+// key returns the encryption key, creating it if create is set.
+func (f *File) key(create bool) ([]byte, error) {
+	key, err := os.ReadFile(f.KeyPath)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("invalid secret key in %s", f.KeyPath)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) || !create {
+		return nil, err
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	// the key is never replaced, since the secrets encrypted with it could
+	// not be read anymore. If another process created it first, its key is
+	// used instead.
+	err = writeExclusive(f.KeyPath, key)
+	if errors.Is(err, os.ErrExist) {
+		return f.key(false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (f *File) read() (map[string]string, error) {
+	secrets := map[string]string{}
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := f.key(false)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read secret key: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("corrupted secrets file %s", f.Path)
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt %s: %w", f.Path, err)
+	}
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+func (f *File) write(secrets map[string]string) error {
+	key, err := f.key(true)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	return writePrivate(f.Path, aead.Seal(nonce, nonce, plain, nil))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writeExclusive creates the file at path with data, readable only by the
+// user. It fails with os.ErrExist if the file exists.
+func writeExclusive(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// writePrivate atomically replaces the file at path with data, readable only
+// by the user.
+func writePrivate(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ensure that File implements the Store interface
+var _ Store = (*File)(nil)
@@ -0,0 +1,36 @@
+package secret
+
+import (
+	"errors"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Keyring stores secrets in the OS keyring: the Secret Service on Linux,
+// the Keychain on macOS and the Credential Manager on Windows.
+type Keyring struct {
+	Service string
+}
+
+func (k *Keyring) Get(name string) (string, error) {
+	value, err := keyring.Get(k.Service, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", &NotFoundError{Name: name}
+	}
+	return value, err
+}
+
+func (k *Keyring) Set(name, value string) error {
+	return keyring.Set(k.Service, name, value)
+}
+
+func (k *Keyring) Delete(name string) error {
+	err := keyring.Delete(k.Service, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// ensure that Keyring implements the Store interface
+var _ Store = (*Keyring)(nil)
@@ -0,0 +1,49 @@
+// Package secret stores api keys outside of the plaintext profiles, in the
+// OS keyring or in an encrypted file.
+package secret
+
+import (
+	"fmt"
+)
+
+// the backends a Store can use
+const (
+	BackendKeyring = "keyring"
+	BackendFile    = "file"
+)
+
+// Store keeps named secrets.
+type Store interface {
+	// Get returns the secret called name, or a *NotFoundError if there is
+	// none.
+	Get(name string) (string, error)
+	// Set stores value as the secret called name.
+	Set(name, value string) error
+	// Delete removes the secret called name. Deleting a missing secret is
+	// not an error.
+	Delete(name string) error
+}
+
+// New returns the Store of backend. dir is the directory the file backend
+// keeps its files in.
+func New(backend string, dir string) (Store, error) {
+	switch backend {
+	case BackendKeyring:
+		return &Keyring{Service: "hlp"}, nil
+	case BackendFile:
+		return NewFile(dir), nil
+	default:
+		return nil, fmt.Errorf("unknown secret backend %q: expected %s or %s", backend, BackendKeyring, BackendFile)
+	}
+}
+
+// Mask hides all but the last four characters of a secret.
+func Mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 8 {
+		return "********"
+	}
+	return "********" + value[len(value)-4:]
+}
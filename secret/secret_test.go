@@ -0,0 +1,118 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+
+	_, err := store.Get("missing")
+	var notFound *NotFoundError
+	require.True(t, errors.As(err, &notFound), "unexpected error %v", err)
+
+	require.NoError(t, store.Set("key", "sk-secret"))
+	value, err := store.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-secret", value)
+
+	require.NoError(t, store.Delete("key"))
+	require.NoError(t, store.Delete("key"))
+	_, err = store.Get("key")
+	assert.True(t, errors.As(err, &notFound))
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFile(dir)
+	testStore(t, store)
+
+	require.NoError(t, store.Set("key", "sk-secret"))
+	data, err := os.ReadFile(store.Path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "sk-secret")
+
+	if runtime.GOOS != "windows" {
+		for _, path := range []string{store.Path, store.KeyPath} {
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), path)
+		}
+	}
+
+	// a new store reads the secrets written by another one
+	value, err := NewFile(dir).Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-secret", value)
+}
+
+func TestFileConcurrent(t *testing.T) {
+	// every store stands for another process saving its first secret
+	dir := t.TempDir()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			assert.NoError(t, NewFile(dir).Set(name, "sk-"+name))
+		}(fmt.Sprint("key", i))
+	}
+	wg.Wait()
+
+	store := NewFile(dir)
+	for i := 0; i < 8; i++ {
+		name := fmt.Sprint("key", i)
+		value, err := store.Get(name)
+		require.NoError(t, err)
+		assert.Equal(t, "sk-"+name, value)
+	}
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "only the secrets and the key are left")
+}
+
+func TestFileWrongKey(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, NewFile(dir).Set("key", "sk-secret"))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secrets.key"), make([]byte, 32), 0600))
+
+	_, err := NewFile(dir).Get("key")
+	assert.ErrorContains(t, err, "cannot decrypt")
+}
+
+func TestKeyring(t *testing.T) {
+	keyring.MockInit()
+	testStore(t, &Keyring{Service: "hlp-test"})
+}
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+
+	value, err := Command(context.Background(), "echo '  sk-from-command  '")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-from-command", value)
+
+	_, err = Command(context.Background(), "echo oops >&2; exit 3")
+	var cmdErr *CommandError
+	require.True(t, errors.As(err, &cmdErr))
+	assert.Equal(t, "oops", cmdErr.Stderr)
+}
+
+func TestMask(t *testing.T) {
+	assert.Equal(t, "", Mask(""))
+	assert.Equal(t, "********", Mask("short"))
+	assert.Equal(t, "********cdef", Mask("sk-0123456789abcdef"))
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package secret
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// shellCommand runs command with cmd.exe. The command line is passed
+// verbatim, since cmd.exe does not follow the quoting rules exec applies to
+// arguments.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "cmd.exe")
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: `cmd.exe /S /C "` + command + `"`}
+	return cmd
+}
@@ -0,0 +1,126 @@
+package session
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/parse"
+)
+
+// ResumeMessage asks the model to continue a partial reply.
+const ResumeMessage = "Your previous reply was interrupted. Continue it exactly where it stopped, without repeating any of it."
+
+// ChatOptions controls how a chat file is prepared for its next reply.
+type ChatOptions struct {
+	// Regenerate drops the trailing assistant reply so that it is generated
+	// again.
+	Regenerate bool
+	// Resume continues the trailing partial reply. A partial reply that
+	// is still empty is generated from the start.
+	Resume bool
+	// Render, if set, rewrites the parsed messages, for example to render
+	// them as templates. The chat file itself is left untouched.
+	Render func([]chat.Message) ([]chat.Message, error)
+}
+
+// ChatFile is a chat file prepared for generating its next reply.
+type ChatFile struct {
+	// Input is the chat file the replies are appended to. It differs from
+	// the original file when regenerating or resuming a reply.
+	Input    string
+	Messages []chat.Message
+	// Prefix is the beginning of the reply when resuming a partial reply.
+	Prefix string
+}
+
+// PrepareChat parses the chat file input into the request for its next
+// reply.
+func PrepareChat(input string, opts ChatOptions) (ChatFile, error) {
+	blocks, err := parse.ParseBlocks(strings.NewReader(input))
+	if err != nil {
+		return ChatFile{}, err
+	}
+
+	var partial *parse.Block
+	switch {
+	case opts.Regenerate && opts.Resume:
+		return ChatFile{}, fmt.Errorf("cannot both regenerate and resume a reply")
+	case opts.Regenerate:
+		input, blocks, err = dropReply(input, blocks)
+	case opts.Resume:
+		if len(blocks) == 0 || !blocks[len(blocks)-1].Partial {
+			return ChatFile{}, fmt.Errorf("nothing to resume: the chat file does not end with a partial reply")
+		}
+		partial = &blocks[len(blocks)-1]
+		input, blocks, err = dropReply(input, blocks)
+	}
+	if err != nil {
+		return ChatFile{}, err
+	}
+
+	messages, err := parse.Messages(blocks)
+	if err != nil {
+		return ChatFile{}, err
+	}
+
+	if opts.Render != nil {
+		messages, err = opts.Render(messages)
+		if err != nil {
+			return ChatFile{}, err
+		}
+	}
+
+	file := ChatFile{Input: input, Messages: messages}
+	if partial != nil && strings.TrimSpace(partial.Content) != "" {
+		file.Prefix = strings.TrimSuffix(partial.Content, "\n")
+		file.Messages = append(
+			file.Messages,
+			chat.Message{Role: "assistant", Content: file.Prefix},
+			chat.Message{Role: "user", Content: ResumeMessage},
+		)
+	}
+	return file, nil
+}
+
+// dropReply drops the trailing assistant turn, including all of its
+// alternatives, from the chat file so that it can be generated again.
+func dropReply(input string, blocks []parse.Block) (string, []parse.Block, error) {
+	idx := parse.LastTurn(blocks)
+	if idx == len(blocks) || blocks[idx].Role != "assistant" {
+		return "", nil, fmt.Errorf("nothing to regenerate: the chat file does not end with an assistant reply")
+	}
+
+	input = strings.TrimRight(input[:blocks[idx].Offset], "\r\n")
+	if input != "" {
+		input += "\n"
+	}
+	return input, blocks[:idx], nil
+}
+
+// WriteChat writes the chat file input followed by the assistant replies in
+// contents. Several replies are marked as alternatives, see
+// parse.ParseBlocks.
+func WriteChat(writer io.Writer, input string, contents []string) error {
+	output := bufio.NewWriter(writer)
+	if _, err := output.WriteString(input); err != nil {
+		return err
+	}
+
+	if input != "" {
+		output.WriteRune('\n')
+		if input[len(input)-1] != '\n' { // add an extra line if needed
+			output.WriteRune('\n')
+		}
+	}
+	for idx, content := range contents {
+		boundary := parse.FormatBoundary("assistant", idx+1, len(contents))
+		if _, err := fmt.Fprintf(output, "%s\n%s\n", boundary, content); err != nil {
+			return err
+		}
+	}
+
+	return output.Flush()
+}
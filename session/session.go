@@ -0,0 +1,82 @@
+// Package session holds the conversation logic behind hlp ask and hlp chat:
+// building the messages of a question, streaming replies and preparing chat
+// files for their next reply.
+package session
+
+import (
+	"context"
+	"strings"
+
+	"github.com/yiblet/hlp/chat"
+)
+
+// Question returns the messages that ask content. With a system prompt,
+// content is the user's message. Without one, content itself is sent as the
+// system message.
+func Question(system, content string) []chat.Message {
+	if system == "" {
+		return []chat.Message{
+			{Role: "system", Content: content},
+		}
+	}
+	return []chat.Message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: content},
+	}
+}
+
+// Conversation is a series of messages exchanged with a model.
+type Conversation struct {
+	Streamer    chat.Streamer
+	Model       string
+	MaxTokens   int
+	Temperature *float32
+	Messages    []chat.Message
+}
+
+// Add appends a message to the conversation.
+func (c *Conversation) Add(role, content string) {
+	c.Messages = append(c.Messages, chat.Message{Role: role, Content: content})
+}
+
+// Input returns the request for the next reply.
+func (c *Conversation) Input() chat.Input {
+	return chat.Input{
+		Messages:    c.Messages,
+		MaxTokens:   c.MaxTokens,
+		Temperature: c.Temperature,
+		Model:       c.Model,
+	}
+}
+
+// Generate streams the next reply to onData and returns it without adding it
+// to the conversation. If the stream fails, the partial reply is returned
+// together with the error.
+func (c *Conversation) Generate(ctx context.Context, onData func(message string) error) (string, error) {
+	var reply strings.Builder
+	err := c.Streamer.ChatStream(ctx, c.Input(), func(message string) error {
+		reply.WriteString(message)
+		if onData == nil {
+			return nil
+		}
+		return onData(message)
+	})
+	return reply.String(), err
+}
+
+// Reply is like Generate but adds a complete reply to the conversation as an
+// assistant message.
+func (c *Conversation) Reply(ctx context.Context, onData func(message string) error) (string, error) {
+	reply, err := c.Generate(ctx, onData)
+	if err != nil {
+		return reply, err
+	}
+	c.Add("assistant", reply)
+	return reply, nil
+}
+
+// Ask adds content as a user message and returns the reply to it.
+func (c *Conversation) Ask(ctx context.Context, content string, onData func(message string) error) (string, error) {
+	c.Add("user", content)
+	return c.Reply(ctx, onData)
+}
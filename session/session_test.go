@@ -0,0 +1,97 @@
+package session
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yiblet/hlp/chat"
+)
+
+func TestQuestion(t *testing.T) {
+	assert.Equal(t, []chat.Message{{Role: "system", Content: "hi"}}, Question("", "hi"))
+	assert.Equal(t, []chat.Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "hi"},
+	}, Question("be brief", "hi"))
+}
+
+func TestConversation(t *testing.T) {
+	conversation := &Conversation{
+		Streamer: &chat.FakeStreamer{Responses: []string{"first reply", "second reply"}},
+		Model:    "test",
+		Messages: Question("", "hi"),
+	}
+
+	var out strings.Builder
+	reply, err := conversation.Reply(context.Background(), func(message string) error {
+		out.WriteString(message)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "first reply", reply)
+	assert.Equal(t, "first reply", out.String())
+
+	reply, err = conversation.Ask(context.Background(), "again", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "second reply", reply)
+	assert.Equal(t, []chat.Message{
+		{Role: "system", Content: "hi"},
+		{Role: "assistant", Content: "first reply"},
+		{Role: "user", Content: "again"},
+		{Role: "assistant", Content: "second reply"},
+	}, conversation.Messages)
+}
+
+func TestConversationKeepsFailedReplyOut(t *testing.T) {
+	conversation := &Conversation{
+		Streamer: &chat.FakeStreamer{Responses: []string{"a long reply"}, Failure: chat.FakeTruncate},
+		Messages: Question("", "hi"),
+	}
+
+	_, err := conversation.Reply(context.Background(), nil)
+	require.Error(t, err)
+	assert.Len(t, conversation.Messages, 1)
+}
+
+func TestPrepareChat(t *testing.T) {
+	input := "--- user\nhi\n--- assistant\nhello\n"
+
+	file, err := PrepareChat(input, ChatOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, input, file.Input)
+	assert.Len(t, file.Messages, 2)
+
+	file, err = PrepareChat(input, ChatOptions{Regenerate: true})
+	require.NoError(t, err)
+	assert.Equal(t, "--- user\nhi\n", file.Input)
+	assert.Equal(t, []chat.Message{{Role: "user", Content: "hi\n"}}, file.Messages)
+
+	_, err = PrepareChat(input, ChatOptions{Resume: true})
+	assert.ErrorContains(t, err, "nothing to resume")
+
+	_, err = PrepareChat("--- user\nhi\n", ChatOptions{Regenerate: true})
+	assert.ErrorContains(t, err, "nothing to regenerate")
+}
+
+func TestPrepareChatResume(t *testing.T) {
+	input := "--- user\nhi\n--- assistant (partial)\nhel\n"
+
+	file, err := PrepareChat(input, ChatOptions{Resume: true})
+	require.NoError(t, err)
+	assert.Equal(t, "--- user\nhi\n", file.Input)
+	assert.Equal(t, "hel", file.Prefix)
+	assert.Equal(t, []chat.Message{
+		{Role: "user", Content: "hi\n"},
+		{Role: "assistant", Content: "hel"},
+		{Role: "user", Content: ResumeMessage},
+	}, file.Messages)
+}
+
+func TestWriteChat(t *testing.T) {
+	var out strings.Builder
+	require.NoError(t, WriteChat(&out, "--- user\nhi\n", []string{"one", "two"}))
+	assert.Equal(t, "--- user\nhi\n\n--- assistant [1/2]\none\n--- assistant [2/2]\ntwo\n", out.String())
+}
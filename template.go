@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/yiblet/hlp/chat"
+	"github.com/yiblet/hlp/prompt"
+)
+
+// templateArgs are the flags shared by commands that render their prompts
+// as templates. Templating is only enabled when one of the flags is passed
+// so that prompts containing literal "{{" keep working.
+type templateArgs struct {
+	Vars     []string `arg:"--var,separate" help:"set a template variable as key=value, implies --template"`
+	VarsFile string   `arg:"--vars-file" help:"read template variables from a file of key=value lines, implies --template"`
+	Template bool     `arg:"--template" help:"render the prompt as a go text/template"`
+}
+
+func (t *templateArgs) enabled() bool {
+	return t.Template || t.VarsFile != "" || len(t.Vars) > 0
+}
+
+// vars collects the template variables. Variables passed with --var take
+// precedence over the ones in --vars-file.
+func (t *templateArgs) vars() (prompt.Vars, error) {
+	vars := prompt.Vars{}
+	if t.VarsFile != "" {
+		var err error
+		vars, err = prompt.ReadVarsFile(t.VarsFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read vars file: %w", err)
+		}
+	}
+
+	if err := vars.Set(t.Vars...); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+func (t *templateArgs) render(text string) (string, error) {
+	if !t.enabled() {
+		return text, nil
+	}
+
+	vars, err := t.vars()
+	if err != nil {
+		return "", err
+	}
+	return prompt.Render(text, vars)
+}
+
+// renderMessages renders the system and user messages. Assistant replies are
+// passed through unchanged, since a past answer containing "{{" is not a
+// template.
+func (t *templateArgs) renderMessages(messages []chat.Message) ([]chat.Message, error) {
+	if !t.enabled() {
+		return messages, nil
+	}
+
+	vars, err := t.vars()
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := make([]chat.Message, len(messages))
+	for i, msg := range messages {
+		if msg.Role == "assistant" {
+			rendered[i] = msg
+			continue
+		}
+		content, err := prompt.Render(msg.Content, vars)
+		if err != nil {
+			return nil, fmt.Errorf("cannot render %s message %d: %w", msg.Role, i+1, err)
+		}
+		rendered[i] = chat.Message{Role: msg.Role, Content: content}
+	}
+	return rendered, nil
+}